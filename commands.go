@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// commands run as "blockchain <command> [flags] <path>" against the admin API of a running node.
+var commands = map[string]func(args []string) error{
+	"export-chain": exportChain,
+	"import-chain": importChain,
+}
+
+func runCommand(args []string) error {
+	command, ok := commands[args[0]]
+	if !ok {
+		names := []string{}
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown command %q, expected one of: %s", args[0], strings.Join(names, ", "))
+	}
+	return command(args[1:])
+}
+
+// adminFlags are the flags of every command: where the node serves its admin API, and the token it
+// requires from callers that are not on its host.
+type adminFlags struct {
+	node  *string
+	token *string
+}
+
+func newAdminFlags(flags *flag.FlagSet) adminFlags {
+	return adminFlags{
+		node:  flags.String("node", "localhost:3000", "admin address of the node, without TLS"),
+		token: flags.String("token", "", "admin token of the node"),
+	}
+}
+
+// call dials the node and runs run with its admin client.
+func (f adminFlags) call(run func(ctx context.Context, admin proto.AdminClient) error) error {
+	conn, err := grpc.NewClient(*f.node, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to dial %s: %w", *f.node, err)
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	if *f.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+*f.token)
+	}
+	return run(ctx, proto.NewAdminClient(conn))
+}
+
+// chainFilePath returns the path argument of a chain file command. The node reads and writes the file on its
+// own filesystem, so a relative path is made absolute for nodes running on the same host.
+func chainFilePath(flags *flag.FlagSet) (string, error) {
+	if flags.NArg() != 1 {
+		return "", fmt.Errorf("usage: blockchain %s [flags] <path>", flags.Name())
+	}
+	return filepath.Abs(flags.Arg(0))
+}
+
+func exportChain(args []string) error {
+	flags := flag.NewFlagSet("export-chain", flag.ContinueOnError)
+	admin := newAdminFlags(flags)
+	from := flags.Int("from", 0, "height of the first block to export")
+	to := flags.Int("to", 0, "height of the last block to export, the tip when 0")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	path, err := chainFilePath(flags)
+	if err != nil {
+		return err
+	}
+
+	return admin.call(func(ctx context.Context, client proto.AdminClient) error {
+		info, err := client.ExportChain(ctx, &proto.ChainExportRequest{Path: path, FromHeight: int32(*from), ToHeight: int32(*to)})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("exported blocks %d-%d to %s, checksum %x\n", info.FromHeight, info.ToHeight, path, info.Checksum)
+		return nil
+	})
+}
+
+func importChain(args []string) error {
+	flags := flag.NewFlagSet("import-chain", flag.ContinueOnError)
+	admin := newAdminFlags(flags)
+	trusted := flags.Int("trusted", 0, "height below which block signatures are not verified")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	path, err := chainFilePath(flags)
+	if err != nil {
+		return err
+	}
+
+	return admin.call(func(ctx context.Context, client proto.AdminClient) error {
+		info, err := client.ImportChain(ctx, &proto.ChainImportRequest{Path: path, TrustedHeight: int32(*trusted)})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("imported blocks %d-%d from %s, chain height %d\n", info.FromHeight, info.ToHeight, path, info.Height)
+		return nil
+	})
+}
@@ -43,6 +43,10 @@ func (p *PrivateKey) Bytes() []byte {
 	return p.key
 }
 
+func (p *PrivateKey) Seed() []byte {
+	return p.key.Seed()
+}
+
 func (p *PrivateKey) Sign(data []byte) *Signature {
 	return &Signature{
 		data: ed25519.Sign(p.Bytes(), data),
@@ -65,14 +69,14 @@ func (p *PublicKey) Bytes() []byte {
 	return p.key
 }
 
-func PublicKeyFromBytes(data []byte) *PublicKey {
+func PublicKeyFromBytes(data []byte) (*PublicKey, error) {
 	if len(data) != publicKeySize {
-		panic(fmt.Errorf(`invalid public key size. Size must be %d, but got %d`, publicKeySize, len(data)))
+		return nil, fmt.Errorf(`invalid public key size. Size must be %d, but got %d`, publicKeySize, len(data))
 	}
 
 	return &PublicKey{
 		key: ed25519.PublicKey(data),
-	}
+	}, nil
 }
 
 // ====================================================================================================
@@ -85,13 +89,13 @@ func (s *Signature) Bytes() []byte {
 	return s.data
 }
 
-func SignatureFromBytes(data []byte) *Signature {
+func SignatureFromBytes(data []byte) (*Signature, error) {
 	if len(data) != signatureSize {
-		panic(fmt.Errorf(`invalid signature size. Size must be %d, but got %d`, signatureSize, len(data)))
+		return nil, fmt.Errorf(`invalid signature size. Size must be %d, but got %d`, signatureSize, len(data))
 	}
 	return &Signature{
 		data: data,
-	}
+	}, nil
 }
 
 func (s *Signature) Verify(pubKey *PublicKey, data []byte) bool {
@@ -110,6 +114,25 @@ func (p *PublicKey) Address() *Address {
 	}
 }
 
+func AddressFromBytes(data []byte) (*Address, error) {
+	if len(data) != addressSize {
+		return nil, fmt.Errorf(`invalid address size. Size must be %d, but got %d`, addressSize, len(data))
+	}
+
+	return &Address{
+		data: data,
+	}, nil
+}
+
+func AddressFromString(s string) (*Address, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+
+	return AddressFromBytes(data)
+}
+
 func (a *Address) Bytes() []byte {
 	return a.data
 }
@@ -37,6 +37,14 @@ func TestGeneratePrivateKeyFromSeedDeterministically(t *testing.T) {
 	assert.Equal(t, pk.Bytes(), pk2.Bytes())
 }
 
+func TestPrivateKeySeedRoundTrip(t *testing.T) {
+	pk := GeneratePrivateKey()
+
+	pk2 := GeneratePrivateKeyFromSeed(pk.Seed())
+
+	assert.Equal(t, pk.Bytes(), pk2.Bytes())
+}
+
 func TestPublicKeyFromPrivateKey(t *testing.T) {
 	pk := getStaticPrivateKey()
 	pub := pk.Public()
@@ -132,3 +140,71 @@ func TestAddressString(t *testing.T) {
 
 	assert.Equal(t, "11a293f4", addr.String())
 }
+
+func TestAddressFromString(t *testing.T) {
+	pub := getStaticPrivateKey().Public()
+
+	addr, err := AddressFromString(pub.Address().String())
+	assert.NoError(t, err)
+	assert.Equal(t, pub.Address().Bytes(), addr.Bytes())
+
+	_, err = AddressFromString("11a293f4")
+	assert.Error(t, err)
+	_, err = AddressFromString("not hex")
+	assert.Error(t, err)
+	_, err = AddressFromBytes(make([]byte, addressSize+1))
+	assert.Error(t, err)
+}
+
+func TestFromBytesRejectsWrongSizes(t *testing.T) {
+	_, err := PublicKeyFromBytes(make([]byte, publicKeySize-1))
+	assert.Error(t, err)
+	_, err = SignatureFromBytes(make([]byte, signatureSize+1))
+	assert.Error(t, err)
+}
+
+func FuzzPublicKeyFromBytes(f *testing.F) {
+	f.Add(getStaticPrivateKey().Public().Bytes())
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pub, err := PublicKeyFromBytes(data)
+		if err != nil {
+			assert.NotEqual(t, publicKeySize, len(data))
+			return
+		}
+		assert.Len(t, pub.Address().Bytes(), addressSize)
+	})
+}
+
+func FuzzSignatureVerify(f *testing.F) {
+	pk := getStaticPrivateKey()
+	f.Add(pk.Public().Bytes(), pk.Sign([]byte("hello world")).Bytes(), []byte("hello world"))
+	f.Add(pk.Public().Bytes(), make([]byte, signatureSize), []byte{})
+
+	f.Fuzz(func(t *testing.T, publicKey []byte, signature []byte, data []byte) {
+		pub, err := PublicKeyFromBytes(publicKey)
+		if err != nil {
+			return
+		}
+		sig, err := SignatureFromBytes(signature)
+		if err != nil {
+			assert.NotEqual(t, signatureSize, len(signature))
+			return
+		}
+		sig.Verify(pub, data)
+	})
+}
+
+func FuzzAddressFromString(f *testing.F) {
+	f.Add(getStaticPrivateKey().Public().Address().String())
+	f.Add("not hex")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		addr, err := AddressFromString(s)
+		if err != nil {
+			return
+		}
+		assert.Len(t, addr.Bytes(), addressSize)
+	})
+}
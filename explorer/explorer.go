@@ -0,0 +1,254 @@
+package explorer
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/hex"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const pageSize = 20
+
+//go:embed templates static
+var content embed.FS
+
+// PeerLister lists the peers of the node. proto.AdminClient implements it.
+type PeerLister interface {
+	GetPeerInfo(ctx context.Context, in *proto.Ack, opts ...grpc.CallOption) (*proto.PeerInfoList, error)
+}
+
+// Explorer is a read-only web UI over the Query service of a node. Its pages are rendered on the server from
+// the templates embedded in the binary, so it needs neither JavaScript nor files on disk.
+type Explorer struct {
+	query proto.QueryClient
+	peers PeerLister
+	pages map[string]*template.Template
+	mux   *http.ServeMux
+}
+
+func New(query proto.QueryClient, peers PeerLister) *Explorer {
+	e := &Explorer{
+		query: query,
+		peers: peers,
+		pages: map[string]*template.Template{},
+		mux:   http.NewServeMux(),
+	}
+	for _, page := range []string{"index", "block", "transaction", "address", "mempool", "peers", "error"} {
+		e.pages[page] = template.Must(template.New("layout.html").Funcs(funcs).ParseFS(content,
+			"templates/layout.html", "templates/"+page+".html"))
+	}
+
+	static, _ := fs.Sub(content, "static")
+	e.mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	e.route("GET /{$}", e.index)
+	e.route("GET /blocks/{id}", e.block)
+	e.route("GET /tx/{hash}", e.transaction)
+	e.route("GET /address/{address}", e.address)
+	e.route("GET /mempool", e.mempool)
+	e.route("GET /peers", e.peerList)
+	return e
+}
+
+func (e *Explorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.ServeHTTP(w, r)
+}
+
+// route renders the page named by handler with the data it returns, or the error page.
+func (e *Explorer) route(pattern string, handler func(*http.Request) (string, interface{}, error)) {
+	e.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		page, data, err := handler(r)
+		code := http.StatusOK
+		if err != nil {
+			page, data, code = "error", status.Convert(err).Message(), httpStatus(err)
+		}
+		body := bytes.Buffer{}
+		if err := e.pages[page].Execute(&body, view{Root: root(r.URL.Path), Data: data}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(code)
+		w.Write(body.Bytes())
+	})
+}
+
+// view is what pages are rendered with. Links are relative to Root, so the explorer works wherever it is mounted.
+type view struct {
+	Root string
+	Data interface{}
+}
+
+// root is the relative path from the page at path back to the explorer root, "../" for "/blocks/1".
+func root(path string) string {
+	depth := strings.Count(strings.TrimPrefix(path, "/"), "/")
+	if depth == 0 {
+		return "./"
+	}
+	return strings.Repeat("../", depth)
+}
+
+type indexPage struct {
+	Tip           *proto.ChainTip
+	Blocks        []*proto.Block
+	NextPageToken string
+}
+
+func (e *Explorer) index(r *http.Request) (string, interface{}, error) {
+	tip, err := e.query.GetTip(r.Context(), &proto.Ack{})
+	if err != nil {
+		return "", nil, err
+	}
+	list, err := e.query.ListBlocks(r.Context(), pageRequest(r))
+	if err != nil {
+		return "", nil, err
+	}
+	return "index", indexPage{Tip: tip, Blocks: list.Blocks, NextPageToken: list.NextPageToken}, nil
+}
+
+// block looks the block up by height when id is a number, by hash otherwise.
+func (e *Explorer) block(r *http.Request) (string, interface{}, error) {
+	id := r.PathValue("id")
+	if height, err := strconv.ParseInt(id, 10, 32); err == nil {
+		block, err := e.query.GetBlockByHeight(r.Context(), &proto.HeightRequest{Height: int32(height)})
+		return "block", block, err
+	}
+	hash, err := hexParam(id, "block hash")
+	if err != nil {
+		return "", nil, err
+	}
+	block, err := e.query.GetBlockByHash(r.Context(), &proto.HashRequest{Hash: hash})
+	return "block", block, err
+}
+
+func (e *Explorer) transaction(r *http.Request) (string, interface{}, error) {
+	hash, err := hexParam(r.PathValue("hash"), "transaction hash")
+	if err != nil {
+		return "", nil, err
+	}
+	info, err := e.query.GetTransaction(r.Context(), &proto.HashRequest{Hash: hash})
+	return "transaction", info, err
+}
+
+type addressPage struct {
+	Address []byte
+	Balance *proto.Balance
+	UTXOs   []*proto.UTXO
+	History *proto.AddressHistory
+	// HistoryUnavailable explains why History is missing, typically because the address index is disabled.
+	HistoryUnavailable string
+}
+
+func (e *Explorer) address(r *http.Request) (string, interface{}, error) {
+	address, err := hexParam(r.PathValue("address"), "address")
+	if err != nil {
+		return "", nil, err
+	}
+	page := addressPage{Address: address}
+	req := &proto.AddressRequest{Address: address}
+	if page.Balance, err = e.query.GetBalance(r.Context(), req); err != nil {
+		return "", nil, err
+	}
+	utxos, err := e.query.GetUTXOs(r.Context(), req)
+	if err != nil {
+		return "", nil, err
+	}
+	page.UTXOs = utxos.Utxos
+
+	history := pageRequest(r)
+	page.History, err = e.query.GetAddressHistory(r.Context(), &proto.AddressRequest{
+		Address:   address,
+		PageSize:  history.PageSize,
+		PageToken: history.PageToken,
+	})
+	if err != nil {
+		page.HistoryUnavailable = status.Convert(err).Message()
+	}
+	return "address", page, nil
+}
+
+func (e *Explorer) mempool(r *http.Request) (string, interface{}, error) {
+	list, err := e.query.GetMempool(r.Context(), pageRequest(r))
+	return "mempool", list, err
+}
+
+func (e *Explorer) peerList(r *http.Request) (string, interface{}, error) {
+	list, err := e.peers.GetPeerInfo(r.Context(), &proto.Ack{})
+	return "peers", list, err
+}
+
+func pageRequest(r *http.Request) *proto.PageRequest {
+	return &proto.PageRequest{PageSize: pageSize, PageToken: r.URL.Query().Get("page")}
+}
+
+func hexParam(value string, name string) ([]byte, error) {
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s %q", name, value)
+	}
+	return b, nil
+}
+
+func httpStatus(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.FailedPrecondition, codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+var funcs = template.FuncMap{
+	"hex":       hex.EncodeToString,
+	"blockHash": types.HashBlockSHA256,
+	"txHash":    types.HashTransactionSHA256,
+	"short": func(b []byte) string {
+		s := hex.EncodeToString(b)
+		if len(s) > 16 {
+			return s[:8] + "…" + s[len(s)-8:]
+		}
+		return s
+	},
+	// address is the address owning publicKey, or empty when publicKey is malformed.
+	"address": func(publicKey []byte) string {
+		key, err := crypto.PublicKeyFromBytes(publicKey)
+		if err != nil {
+			return ""
+		}
+		return key.Address().String()
+	},
+	"time": func(nanos int64) string {
+		if nanos == 0 {
+			return "-"
+		}
+		return time.Unix(0, nanos).UTC().Format(time.RFC3339)
+	},
+	"unix": func(seconds int64) string {
+		return time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+	},
+	"total": func(outputs []*proto.TxOutput) int64 {
+		total := int64(0)
+		for _, output := range outputs {
+			total += output.Amount
+		}
+		return total
+	},
+}
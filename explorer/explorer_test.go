@@ -0,0 +1,146 @@
+package explorer
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type stubQuery struct {
+	proto.QueryClient
+	blocks []*proto.Block
+}
+
+func (q *stubQuery) GetTip(ctx context.Context, _ *proto.Ack, _ ...grpc.CallOption) (*proto.ChainTip, error) {
+	tip := q.blocks[len(q.blocks)-1]
+	return &proto.ChainTip{Height: tip.Header.Height, Hash: types.HashBlockSHA256(tip)}, nil
+}
+
+func (q *stubQuery) ListBlocks(ctx context.Context, _ *proto.PageRequest, _ ...grpc.CallOption) (*proto.BlockList, error) {
+	return &proto.BlockList{Blocks: q.blocks}, nil
+}
+
+func (q *stubQuery) GetBlockByHeight(ctx context.Context, req *proto.HeightRequest, _ ...grpc.CallOption) (*proto.Block, error) {
+	if int(req.Height) >= len(q.blocks) {
+		return nil, status.Errorf(codes.NotFound, "no block at height %d", req.Height)
+	}
+	return q.blocks[req.Height], nil
+}
+
+func (q *stubQuery) GetTransaction(ctx context.Context, req *proto.HashRequest, _ ...grpc.CallOption) (*proto.TransactionInfo, error) {
+	for _, block := range q.blocks {
+		for i, tx := range block.Transaction {
+			if string(types.HashTransactionSHA256(tx)) == string(req.Hash) {
+				return &proto.TransactionInfo{Transaction: tx, Hash: req.Hash, BlockHeight: block.Header.Height, Index: int32(i)}, nil
+			}
+		}
+	}
+	return nil, status.Error(codes.NotFound, "unknown transaction")
+}
+
+func (q *stubQuery) GetBalance(ctx context.Context, req *proto.AddressRequest, _ ...grpc.CallOption) (*proto.Balance, error) {
+	return &proto.Balance{Address: req.Address, Amount: 42}, nil
+}
+
+func (q *stubQuery) GetUTXOs(ctx context.Context, req *proto.AddressRequest, _ ...grpc.CallOption) (*proto.UTXOList, error) {
+	return &proto.UTXOList{}, nil
+}
+
+func (q *stubQuery) GetAddressHistory(ctx context.Context, req *proto.AddressRequest, _ ...grpc.CallOption) (*proto.AddressHistory, error) {
+	return nil, status.Error(codes.FailedPrecondition, "address index is disabled")
+}
+
+func (q *stubQuery) GetMempool(ctx context.Context, _ *proto.PageRequest, _ ...grpc.CallOption) (*proto.MempoolList, error) {
+	tx := q.blocks[1].Transaction[0]
+	return &proto.MempoolList{Transactions: []*proto.MempoolTransaction{{Hash: types.HashTransactionSHA256(tx), Transaction: tx}}, Total: 1}, nil
+}
+
+type stubPeers []*proto.PeerInfo
+
+func (p stubPeers) GetPeerInfo(ctx context.Context, _ *proto.Ack, _ ...grpc.CallOption) (*proto.PeerInfoList, error) {
+	return &proto.PeerInfoList{Peers: p}, nil
+}
+
+func newTestExplorer() (*Explorer, *proto.Transaction, *crypto.PrivateKey) {
+	key := crypto.GeneratePrivateKey()
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs:  []*proto.TxInput{{PreviousTxHash: []byte{0xaa}, PublicKey: key.Public().Bytes()}},
+		Outputs: []*proto.TxOutput{{Amount: 7, DestAddress: []byte{0x0b, 0x0c}}},
+	}
+	query := &stubQuery{blocks: []*proto.Block{
+		{Header: &proto.Header{Height: 0}},
+		{Header: &proto.Header{Height: 1, MerkleRoot: []byte{0xde, 0xad}}, Transaction: []*proto.Transaction{tx}},
+	}}
+	return New(query, stubPeers{{Identity: "peer-identity", Address: "localhost:3001"}}), tx, key
+}
+
+func get(e *Explorer, path string) (int, string) {
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder.Code, recorder.Body.String()
+}
+
+func TestExplorerPages(t *testing.T) {
+	e, tx, key := newTestExplorer()
+	txHash := hex.EncodeToString(types.HashTransactionSHA256(tx))
+
+	code, body := get(e, "/")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `href="./blocks/1"`)
+	assert.Contains(t, body, `href="./static/style.css"`)
+
+	code, body = get(e, "/blocks/1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "dead")
+	assert.Contains(t, body, `href="../tx/`+txHash+`"`)
+
+	code, body = get(e, "/tx/"+txHash)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `href="../address/0b0c"`)
+	assert.Contains(t, body, `href="../address/`+key.Public().Address().String()+`"`)
+
+	code, body = get(e, "/address/0b0c")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "42 at height 0")
+	assert.Contains(t, body, "address index is disabled")
+
+	code, body = get(e, "/mempool")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, txHash)
+
+	code, body = get(e, "/peers")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "peer-identity")
+
+	code, body = get(e, "/static/style.css")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "font-family")
+}
+
+func TestExplorerErrors(t *testing.T) {
+	e, _, _ := newTestExplorer()
+
+	code, body := get(e, "/blocks/9")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Contains(t, body, "no block at height 9")
+
+	code, _ = get(e, "/tx/not-hex")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestRoot(t *testing.T) {
+	assert.Equal(t, "./", root("/"))
+	assert.Equal(t, "./", root("/mempool"))
+	assert.Equal(t, "../", root("/blocks/1"))
+}
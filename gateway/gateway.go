@@ -0,0 +1,226 @@
+package gateway
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const maxBodySize = 1 << 20
+
+//go:embed openapi.json
+var openAPI []byte
+
+// Gateway serves the Query service of a node as JSON over HTTP. Messages are rendered by protojson, with
+// bytes fields in hex. The API is described by the OpenAPI document served at /openapi.json, and its
+// subscriptions are pushed to WebSocket clients connected to /v1/ws.
+type Gateway struct {
+	query             proto.QueryClient
+	mux               *http.ServeMux
+	maxSubscriptions  int
+	heartbeatInterval time.Duration
+	sendBufferSize    int
+}
+
+func New(query proto.QueryClient) *Gateway {
+	g := &Gateway{
+		query:             query,
+		mux:               http.NewServeMux(),
+		maxSubscriptions:  defaultMaxSubscriptions,
+		heartbeatInterval: defaultHeartbeatInterval,
+		sendBufferSize:    defaultSendBufferSize,
+	}
+	g.route("GET /v1/tip", g.getTip)
+	g.route("GET /v1/blocks", g.listBlocks)
+	g.route("GET /v1/blocks/{id}", g.getBlock)
+	g.route("GET /v1/transactions/{hash}", g.getTransaction)
+	g.route("POST /v1/transactions", g.sendTransaction)
+	g.route("GET /v1/addresses/{address}/balance", g.getBalance)
+	g.route("GET /v1/addresses/{address}/utxos", g.getUTXOs)
+	g.route("GET /v1/addresses/{address}/history", g.getAddressHistory)
+	g.route("GET /v1/mempool", g.getMempool)
+	g.mux.Handle("GET /v1/ws", websocket.Server{Handler: g.serveWebSocket})
+	g.mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPI)
+	})
+	return g
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *Gateway) route(pattern string, handler func(*http.Request) (pb.Message, error)) {
+	g.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		msg, err := handler(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		b, err := marshal(msg)
+		if err != nil {
+			writeError(w, status.Error(codes.Internal, err.Error()))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+}
+
+func (g *Gateway) getTip(r *http.Request) (pb.Message, error) {
+	return g.query.GetTip(r.Context(), &proto.Ack{})
+}
+
+func (g *Gateway) listBlocks(r *http.Request) (pb.Message, error) {
+	page, err := pageRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return g.query.ListBlocks(r.Context(), page)
+}
+
+// getBlock looks the block up by height when id is a number, by hash otherwise.
+func (g *Gateway) getBlock(r *http.Request) (pb.Message, error) {
+	id := r.PathValue("id")
+	if height, err := strconv.ParseInt(id, 10, 32); err == nil {
+		return g.query.GetBlockByHeight(r.Context(), &proto.HeightRequest{Height: int32(height)})
+	}
+	hash, err := hexParam(id, "block hash")
+	if err != nil {
+		return nil, err
+	}
+	return g.query.GetBlockByHash(r.Context(), &proto.HashRequest{Hash: hash})
+}
+
+func (g *Gateway) getTransaction(r *http.Request) (pb.Message, error) {
+	hash, err := hexParam(r.PathValue("hash"), "transaction hash")
+	if err != nil {
+		return nil, err
+	}
+	return g.query.GetTransaction(r.Context(), &proto.HashRequest{Hash: hash})
+}
+
+func (g *Gateway) sendTransaction(r *http.Request) (pb.Message, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	tx := &proto.Transaction{}
+	if err := unmarshal(body, tx); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid transaction: %v", err)
+	}
+	return g.query.SendTransaction(r.Context(), tx)
+}
+
+// getBalance returns the current balance, or the balance at the height given as query parameter.
+func (g *Gateway) getBalance(r *http.Request) (pb.Message, error) {
+	address, err := hexParam(r.PathValue("address"), "address")
+	if err != nil {
+		return nil, err
+	}
+	if h := r.URL.Query().Get("height"); h != "" {
+		height, err := strconv.ParseInt(h, 10, 32)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid height %q", h)
+		}
+		return g.query.GetBalanceAtHeight(r.Context(), &proto.BalanceRequest{Address: address, Height: int32(height)})
+	}
+	return g.query.GetBalance(r.Context(), &proto.AddressRequest{Address: address})
+}
+
+func (g *Gateway) getUTXOs(r *http.Request) (pb.Message, error) {
+	req, err := addressRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return g.query.GetUTXOs(r.Context(), req)
+}
+
+func (g *Gateway) getAddressHistory(r *http.Request) (pb.Message, error) {
+	req, err := addressRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return g.query.GetAddressHistory(r.Context(), req)
+}
+
+func (g *Gateway) getMempool(r *http.Request) (pb.Message, error) {
+	page, err := pageRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return g.query.GetMempool(r.Context(), page)
+}
+
+func hexParam(value string, name string) ([]byte, error) {
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s %q", name, value)
+	}
+	return b, nil
+}
+
+func pageRequest(r *http.Request) (*proto.PageRequest, error) {
+	page := &proto.PageRequest{PageToken: r.URL.Query().Get("pageToken")}
+	if s := r.URL.Query().Get("pageSize"); s != "" {
+		size, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page size %q", s)
+		}
+		page.PageSize = int32(size)
+	}
+	return page, nil
+}
+
+func addressRequest(r *http.Request) (*proto.AddressRequest, error) {
+	address, err := hexParam(r.PathValue("address"), "address")
+	if err != nil {
+		return nil, err
+	}
+	page, err := pageRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.AddressRequest{Address: address, PageSize: page.PageSize, PageToken: page.PageToken}, nil
+}
+
+var httpStatus = map[codes.Code]int{
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.Unimplemented:      http.StatusNotImplemented,
+}
+
+// writeError renders gRPC errors as {"code": "NotFound", "message": "..."} with the matching HTTP status.
+func writeError(w http.ResponseWriter, err error) {
+	s := status.Convert(err)
+	code, ok := httpStatus[s.Code()]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{
+		"code":    s.Code().String(),
+		"message": s.Message(),
+	})
+}
@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "google.golang.org/protobuf/proto"
+)
+
+type stubQuery struct {
+	proto.QueryClient
+	blocks map[int32]*proto.Block
+	sent   []*proto.Transaction
+}
+
+func (q *stubQuery) GetBlockByHeight(ctx context.Context, req *proto.HeightRequest, _ ...grpc.CallOption) (*proto.Block, error) {
+	block, ok := q.blocks[req.Height]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no block at height %d", req.Height)
+	}
+	return block, nil
+}
+
+func (q *stubQuery) GetBlockByHash(ctx context.Context, req *proto.HashRequest, _ ...grpc.CallOption) (*proto.Block, error) {
+	for _, block := range q.blocks {
+		if string(block.Header.PreviousHash) == string(req.Hash) {
+			return block, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, "unknown block")
+}
+
+func (q *stubQuery) SendTransaction(ctx context.Context, tx *proto.Transaction, _ ...grpc.CallOption) (*proto.TxReceipt, error) {
+	q.sent = append(q.sent, tx)
+	return &proto.TxReceipt{TxHash: []byte{0xab, 0xcd}}, nil
+}
+
+func request(t *testing.T, g *Gateway, method string, path string, body string) (int, map[string]interface{}) {
+	recorder := httptest.NewRecorder()
+	g.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	response := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	return recorder.Code, response
+}
+
+func TestGatewayRendersBytesInHex(t *testing.T) {
+	previousHash := []byte{0x01, 0x02, 0xff}
+	query := &stubQuery{blocks: map[int32]*proto.Block{
+		1: {Header: &proto.Header{Height: 1, PreviousHash: previousHash, Timestamp: 42}},
+	}}
+	g := New(query)
+
+	code, block := request(t, g, http.MethodGet, "/v1/blocks/1", "")
+	assert.Equal(t, http.StatusOK, code)
+	header := block["header"].(map[string]interface{})
+	assert.Equal(t, "0102ff", header["previousHash"])
+	assert.Equal(t, "42", header["timestamp"])
+	assert.Equal(t, []interface{}{}, block["transaction"])
+
+	code, block = request(t, g, http.MethodGet, "/v1/blocks/0102ff", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(1), block["header"].(map[string]interface{})["height"])
+}
+
+func TestGatewayErrors(t *testing.T) {
+	g := New(&stubQuery{blocks: map[int32]*proto.Block{}})
+
+	code, body := request(t, g, http.MethodGet, "/v1/blocks/7", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "NotFound", body["code"])
+
+	code, body = request(t, g, http.MethodGet, "/v1/transactions/xyz", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "InvalidArgument", body["code"])
+
+	code, _ = request(t, g, http.MethodPost, "/v1/transactions", `{"inputs": [{"publicKey": "not hex"}]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestGatewaySendTransaction(t *testing.T) {
+	query := &stubQuery{}
+	g := New(query)
+
+	code, receipt := request(t, g, http.MethodPost, "/v1/transactions",
+		`{"version": 1, "inputs": [{"previousTxHash": "aa01", "prevOutputIndex": 2, "signature": "beef"}], "outputs": [{"amount": "5", "destAddress": "0a0b"}]}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "abcd", receipt["txHash"])
+
+	expected := &proto.Transaction{
+		Version: 1,
+		Inputs:  []*proto.TxInput{{PreviousTxHash: []byte{0xaa, 0x01}, PrevOutputIndex: 2, Signature: []byte{0xbe, 0xef}}},
+		Outputs: []*proto.TxOutput{{Amount: 5, DestAddress: []byte{0x0a, 0x0b}}},
+	}
+	assert.Len(t, query.sent, 1)
+	assert.True(t, pb.Equal(expected, query.sent[0]))
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs:  []*proto.TxInput{{PreviousTxHash: []byte{1, 2, 3}, PublicKey: []byte{4}}},
+		Outputs: []*proto.TxOutput{{Amount: 7, DestAddress: []byte{5, 6}}},
+	}
+	b, err := marshal(tx)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"previousTxHash":"`+hex.EncodeToString([]byte{1, 2, 3})+`"`)
+
+	decoded := &proto.Transaction{}
+	assert.NoError(t, unmarshal(b, decoded))
+	assert.True(t, pb.Equal(tx, decoded))
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	g := New(&stubQuery{})
+	code, doc := request(t, g, http.MethodGet, "/openapi.json", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	paths := doc["paths"].(map[string]interface{})
+	for _, path := range []string{
+		"/v1/tip", "/v1/blocks", "/v1/blocks/{id}", "/v1/transactions", "/v1/transactions/{hash}",
+		"/v1/addresses/{address}/balance", "/v1/addresses/{address}/utxos", "/v1/addresses/{address}/history",
+		"/v1/mempool",
+	} {
+		assert.Contains(t, paths, path)
+	}
+}
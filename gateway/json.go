@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	pb "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var marshalOptions = protojson.MarshalOptions{EmitUnpopulated: true}
+
+// marshal renders msg in JSON like protojson, except for its bytes fields: protojson renders them in
+// base64, while hashes, addresses, keys and signatures are always shown in hex across the project. The
+// bytes fields are converted walking the JSON along with the message descriptor.
+func marshal(msg pb.Message) ([]byte, error) {
+	b, err := marshalOptions.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	if err := convertBytes(value, msg.ProtoReflect().Descriptor(), base64ToHex); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// unmarshal parses the JSON rendered by marshal into msg, its bytes fields in hex.
+func unmarshal(b []byte, msg pb.Message) error {
+	var value map[string]interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	if err := convertBytes(value, msg.ProtoReflect().Descriptor(), hexToBase64); err != nil {
+		return err
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return protojson.Unmarshal(b, msg)
+}
+
+func base64ToHex(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hexToBase64(s string) (string, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func convertBytes(value map[string]interface{}, desc protoreflect.MessageDescriptor, convert func(string) (string, error)) error {
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.Kind() != protoreflect.BytesKind && field.Kind() != protoreflect.MessageKind {
+			continue
+		}
+		// protojson accepts the original field name as well as the JSON one
+		name := field.JSONName()
+		if _, ok := value[name]; !ok {
+			name = field.TextName()
+		}
+		v, ok := value[name]
+		if !ok || v == nil {
+			continue
+		}
+
+		convertOne := func(v interface{}) (interface{}, error) {
+			if field.Kind() == protoreflect.MessageKind {
+				if m, ok := v.(map[string]interface{}); ok {
+					return m, convertBytes(m, field.Message(), convert)
+				}
+				return v, nil
+			}
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("field %s: expected a string", name)
+			}
+			converted, err := convert(s)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", name, err)
+			}
+			return converted, nil
+		}
+
+		if field.IsList() {
+			list, ok := v.([]interface{})
+			if !ok {
+				continue
+			}
+			for j := range list {
+				converted, err := convertOne(list[j])
+				if err != nil {
+					return err
+				}
+				list[j] = converted
+			}
+			continue
+		}
+		converted, err := convertOne(v)
+		if err != nil {
+			return err
+		}
+		value[name] = converted
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package gateway
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"golang.org/x/net/websocket"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// Topics a WebSocket client can subscribe to.
+const (
+	TopicBlocks  = "blocks"
+	TopicMempool = "mempool"
+	TopicAddress = "address"
+	TopicPeers   = "peers"
+)
+
+const (
+	defaultMaxSubscriptions  = 16
+	defaultHeartbeatInterval = 30 * time.Second
+	defaultSendBufferSize    = 64
+	writeTimeout             = 10 * time.Second
+	maxClientMessageSize     = 4096
+)
+
+// clientMessage is sent by WebSocket clients:
+//
+//	{"action": "subscribe", "topic": "address", "addresses": ["<hex>"]}
+//	{"action": "unsubscribe", "id": "1"}
+//	{"action": "ping"}
+type clientMessage struct {
+	Action    string   `json:"action"`
+	Topic     string   `json:"topic,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	ID        string   `json:"id,omitempty"`
+}
+
+// serverMessage is sent to WebSocket clients. Its type is one of "subscribed", "unsubscribed", "event",
+// "heartbeat", "pong" or "error". Events carry the id of their subscription and the JSON rendering of the
+// streamed Query message in data.
+type serverMessage struct {
+	Type  string          `json:"type"`
+	ID    string          `json:"id,omitempty"`
+	Topic string          `json:"topic,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+	Time  int64           `json:"time,omitempty"`
+}
+
+type wsConn struct {
+	gateway       *Gateway
+	ws            *websocket.Conn
+	ctx           context.Context
+	cancel        context.CancelFunc
+	out           chan serverMessage
+	mu            sync.Mutex
+	subscriptions map[string]context.CancelFunc
+	nextID        int
+}
+
+// serveWebSocket pushes Query subscriptions to the client. Messages are queued per connection, and a client
+// too slow to drain its queue is disconnected so it cannot hold events back for the others.
+func (g *Gateway) serveWebSocket(ws *websocket.Conn) {
+	ws.MaxPayloadBytes = maxClientMessageSize
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &wsConn{
+		gateway:       g,
+		ws:            ws,
+		ctx:           ctx,
+		cancel:        cancel,
+		out:           make(chan serverMessage, g.sendBufferSize),
+		subscriptions: map[string]context.CancelFunc{},
+	}
+	defer ws.Close()
+	defer cancel()
+
+	go c.writeLoop()
+	for {
+		msg := clientMessage{}
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			return
+		}
+		c.handle(msg)
+	}
+}
+
+func (c *wsConn) writeLoop() {
+	defer c.ws.Close()
+	heartbeat := time.NewTicker(c.gateway.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var msg serverMessage
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-heartbeat.C:
+			msg = serverMessage{Type: "heartbeat", Time: time.Now().Unix()}
+		case msg = <-c.out:
+		}
+		c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := websocket.JSON.Send(c.ws, msg); err != nil {
+			c.cancel()
+			return
+		}
+	}
+}
+
+// send queues msg, closing the connection when the client is not keeping up.
+func (c *wsConn) send(msg serverMessage) {
+	select {
+	case c.out <- msg:
+	case <-c.ctx.Done():
+	default:
+		c.cancel()
+	}
+}
+
+func (c *wsConn) handle(msg clientMessage) {
+	switch msg.Action {
+	case "subscribe":
+		if err := c.subscribe(msg); err != nil {
+			c.send(serverMessage{Type: "error", Topic: msg.Topic, Error: err.Error()})
+		}
+	case "unsubscribe":
+		c.mu.Lock()
+		cancel, ok := c.subscriptions[msg.ID]
+		delete(c.subscriptions, msg.ID)
+		c.mu.Unlock()
+		if !ok {
+			c.send(serverMessage{Type: "error", ID: msg.ID, Error: "unknown subscription"})
+			return
+		}
+		cancel()
+		c.send(serverMessage{Type: "unsubscribed", ID: msg.ID})
+	case "ping":
+		c.send(serverMessage{Type: "pong", Time: time.Now().Unix()})
+	default:
+		c.send(serverMessage{Type: "error", Error: fmt.Sprintf("unknown action %q", msg.Action)})
+	}
+}
+
+func (c *wsConn) subscribe(msg clientMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.subscriptions) >= c.gateway.maxSubscriptions {
+		return fmt.Errorf("at most %d subscriptions per connection", c.gateway.maxSubscriptions)
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	recv, err := c.open(ctx, msg)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	c.nextID++
+	id := strconv.Itoa(c.nextID)
+	c.subscriptions[id] = cancel
+	c.send(serverMessage{Type: "subscribed", ID: id, Topic: msg.Topic})
+	go c.forward(ctx, id, msg.Topic, recv)
+	return nil
+}
+
+// open starts the Query stream backing a subscription and returns its Recv.
+func (c *wsConn) open(ctx context.Context, msg clientMessage) (func() (pb.Message, error), error) {
+	query := c.gateway.query
+	switch msg.Topic {
+	case TopicBlocks:
+		stream, err := query.SubscribeTips(ctx, &proto.Ack{})
+		if err != nil {
+			return nil, err
+		}
+		return func() (pb.Message, error) { return stream.Recv() }, nil
+	case TopicMempool:
+		stream, err := query.SubscribeMempool(ctx, &proto.Ack{})
+		if err != nil {
+			return nil, err
+		}
+		return func() (pb.Message, error) { return stream.Recv() }, nil
+	case TopicPeers:
+		stream, err := query.SubscribePeers(ctx, &proto.Ack{})
+		if err != nil {
+			return nil, err
+		}
+		return func() (pb.Message, error) { return stream.Recv() }, nil
+	case TopicAddress:
+		if len(msg.Addresses) == 0 {
+			return nil, fmt.Errorf("no address to watch")
+		}
+		req := &proto.AddressList{}
+		for _, address := range msg.Addresses {
+			b, err := hex.DecodeString(address)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", address)
+			}
+			req.Addresses = append(req.Addresses, b)
+		}
+		stream, err := query.SubscribePayments(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return func() (pb.Message, error) { return stream.Recv() }, nil
+	default:
+		return nil, fmt.Errorf("unknown topic %q", msg.Topic)
+	}
+}
+
+func (c *wsConn) forward(ctx context.Context, id string, topic string, recv func() (pb.Message, error)) {
+	for {
+		msg, err := recv()
+		if err != nil {
+			if ctx.Err() == nil {
+				c.mu.Lock()
+				delete(c.subscriptions, id)
+				c.mu.Unlock()
+				c.send(serverMessage{Type: "error", ID: id, Topic: topic, Error: err.Error()})
+			}
+			return
+		}
+		data, err := marshal(msg)
+		if err != nil {
+			continue
+		}
+		c.send(serverMessage{Type: "event", ID: id, Topic: topic, Data: data})
+	}
+}
@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type stubStreams struct {
+	proto.UnimplementedQueryServer
+	tips chan *proto.TipEvent
+}
+
+func (s *stubStreams) SubscribeTips(_ *proto.Ack, stream proto.Query_SubscribeTipsServer) error {
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case tip := <-s.tips:
+			if err := stream.Send(tip); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func startWebSocketGateway(t *testing.T, configure func(*Gateway)) (*stubStreams, *websocket.Conn) {
+	streams := &stubStreams{tips: make(chan *proto.TipEvent)}
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	proto.RegisterQueryServer(server, streams)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	g := New(proto.NewQueryClient(conn))
+	if configure != nil {
+		configure(g)
+	}
+	httpServer := httptest.NewServer(g)
+	t.Cleanup(httpServer.Close)
+
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/v1/ws"
+	ws, err := websocket.Dial(url, "", httpServer.URL)
+	assert.NoError(t, err)
+	t.Cleanup(func() { ws.Close() })
+	return streams, ws
+}
+
+func exchange(t *testing.T, ws *websocket.Conn, msg clientMessage) serverMessage {
+	assert.NoError(t, websocket.JSON.Send(ws, msg))
+	return receive(t, ws)
+}
+
+func receive(t *testing.T, ws *websocket.Conn) serverMessage {
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reply := serverMessage{}
+	assert.NoError(t, websocket.JSON.Receive(ws, &reply))
+	return reply
+}
+
+func TestWebSocketSubscription(t *testing.T) {
+	streams, ws := startWebSocketGateway(t, nil)
+
+	reply := exchange(t, ws, clientMessage{Action: "subscribe", Topic: TopicBlocks})
+	assert.Equal(t, "subscribed", reply.Type)
+	id := reply.ID
+
+	streams.tips <- &proto.TipEvent{Height: 3, Hash: []byte{0xca, 0xfe}}
+	event := receive(t, ws)
+	assert.Equal(t, "event", event.Type)
+	assert.Equal(t, id, event.ID)
+	assert.Equal(t, TopicBlocks, event.Topic)
+	assert.Contains(t, string(event.Data), `"hash":"cafe"`)
+
+	reply = exchange(t, ws, clientMessage{Action: "unsubscribe", ID: id})
+	assert.Equal(t, "unsubscribed", reply.Type)
+	reply = exchange(t, ws, clientMessage{Action: "unsubscribe", ID: id})
+	assert.Equal(t, "error", reply.Type)
+}
+
+func TestWebSocketRequests(t *testing.T) {
+	_, ws := startWebSocketGateway(t, func(g *Gateway) { g.maxSubscriptions = 1 })
+
+	assert.Equal(t, "pong", exchange(t, ws, clientMessage{Action: "ping"}).Type)
+	assert.Equal(t, "error", exchange(t, ws, clientMessage{Action: "subscribe", Topic: "weather"}).Type)
+	assert.Equal(t, "error", exchange(t, ws, clientMessage{Action: "subscribe", Topic: TopicAddress}).Type)
+	assert.Equal(t, "error", exchange(t, ws, clientMessage{Action: "dance"}).Type)
+
+	assert.Equal(t, "subscribed", exchange(t, ws, clientMessage{Action: "subscribe", Topic: TopicBlocks}).Type)
+	reply := exchange(t, ws, clientMessage{Action: "subscribe", Topic: TopicBlocks})
+	assert.Equal(t, "error", reply.Type)
+	assert.Contains(t, reply.Error, "at most 1 subscriptions")
+}
+
+func TestWebSocketHeartbeat(t *testing.T) {
+	_, ws := startWebSocketGateway(t, func(g *Gateway) { g.heartbeatInterval = 10 * time.Millisecond })
+
+	assert.Equal(t, "heartbeat", receive(t, ws).Type)
+}
+
+func TestWebSocketDisconnectsSlowClients(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &wsConn{ctx: ctx, cancel: cancel, out: make(chan serverMessage, 1)}
+
+	c.send(serverMessage{Type: "event"})
+	assert.NoError(t, ctx.Err())
+	c.send(serverMessage{Type: "event"})
+	assert.Error(t, ctx.Err())
+}
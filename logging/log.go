@@ -4,32 +4,205 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
 
-func LoggerFactory(filePath string) *logrus.Logger {
-	l := logrus.New()
-	l.SetFormatter(&logrus.TextFormatter{
-		TimestampFormat: "2006-01-02 15:04:05",
-		ForceColors:     true,
-		FullTimestamp:   true,
-		DisableColors:   false,
-	})
-
-	parent := path.Dir(filePath)
-	os.MkdirAll(parent, 0o755)
-	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o755)
+// Formats of the log lines.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Components of the node, each with its own logger and level.
+const (
+	ComponentP2P     = "p2p"
+	ComponentChain   = "chain"
+	ComponentMempool = "mempool"
+	ComponentRPC     = "rpc"
+)
+
+type Config struct {
+	// Format is FormatText, the default, or FormatJSON.
+	Format string
+	// Level is the level of every component not listed in Levels, info when empty.
+	Level  string
+	Levels map[string]string
+	// File receives the logs along with stdout. Setting SKIP_STDOUT_LOG=true or Quiet only logs to File.
+	File     string
+	Quiet    bool
+	Rotation Rotation
+}
+
+// Loggers hands out a logger per component. They share the format and output, while their levels can be
+// changed independently at any time.
+type Loggers struct {
+	mu        sync.Mutex
+	level     logrus.Level
+	formatter logrus.Formatter
+	out       *output
+	rotation  Rotation
+	loggers   map[string]*logrus.Logger
+}
+
+func New(config Config) (*Loggers, error) {
+	l := &Loggers{
+		level:    logrus.InfoLevel,
+		out:      &output{quiet: config.Quiet || os.Getenv("SKIP_STDOUT_LOG") == "true"},
+		rotation: config.Rotation,
+		loggers:  map[string]*logrus.Logger{},
+	}
+
+	switch config.Format {
+	case FormatText, "":
+		l.formatter = &logrus.TextFormatter{TimestampFormat: "2006-01-02 15:04:05", FullTimestamp: true}
+	case FormatJSON:
+		l.formatter = &logrus.JSONFormatter{}
+	default:
+		return nil, fmt.Errorf("unknown log format %q", config.Format)
+	}
+	if config.Level != "" {
+		level, err := logrus.ParseLevel(config.Level)
+		if err != nil {
+			return nil, err
+		}
+		l.level = level
+	}
+	for component, level := range config.Levels {
+		if err := l.SetLevel(component, level); err != nil {
+			return nil, err
+		}
+	}
+	if config.File != "" {
+		if err := l.SetFile(config.File); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Logger returns the logger of component, adding a component field to its entries.
+func (l *Loggers) Logger(component string) *logrus.Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.logger(component)
+}
+
+func (l *Loggers) logger(component string) *logrus.Logger {
+	if logger, ok := l.loggers[component]; ok {
+		return logger
+	}
+	logger := logrus.New()
+	logger.SetOutput(l.out)
+	logger.SetFormatter(l.formatter)
+	logger.SetLevel(l.level)
+	logger.AddHook(componentHook(component))
+	l.loggers[component] = logger
+	return logger
+}
+
+// SetLevel changes the level of component, or of every component when component is empty.
+func (l *Loggers) SetLevel(component string, level string) error {
+	parsed, err := logrus.ParseLevel(level)
 	if err != nil {
-		fmt.Println("Error opening file: ", err)
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if component == "" {
+		l.level = parsed
+		for _, logger := range l.loggers {
+			logger.SetLevel(parsed)
+		}
 		return nil
 	}
-	if os.Getenv("SKIP_STDOUT_LOG") == "true" {
-		l.SetOutput(f)
-	} else {
-		l.SetOutput(io.MultiWriter(os.Stdout, f))
+	l.logger(component).SetLevel(parsed)
+	return nil
+}
+
+// Levels returns the level of every component.
+func (l *Loggers) Levels() map[string]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	levels := map[string]string{}
+	for component, logger := range l.loggers {
+		levels[component] = logger.GetLevel().String()
+	}
+	return levels
+}
+
+// SetFile sends the logs to filePath, creating it and its directory when missing, instead of the previous file.
+// The file is rotated as configured by Config.Rotation.
+func (l *Loggers) SetFile(filePath string) error {
+	f, err := openRotatingFile(filePath, l.rotation)
+	if err != nil {
+		return err
+	}
+	l.out.setFile(f)
+	return nil
+}
+
+// Reopen closes and opens the log file again.
+func (l *Loggers) Reopen() error {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+
+	if l.out.file == nil {
+		return nil
+	}
+	return l.out.file.Reopen()
+}
+
+// Close closes the log file, after which the logs only go to stdout.
+func (l *Loggers) Close() error {
+	return l.out.setFile(nil)
+}
+
+// output writes to stdout and the log file. The file can be replaced while loggers write to it.
+type output struct {
+	mu    sync.Mutex
+	quiet bool
+	file  *rotatingFile
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var w io.Writer = os.Stdout
+	switch {
+	case o.file != nil && o.quiet:
+		w = o.file
+	case o.file != nil:
+		w = io.MultiWriter(os.Stdout, o.file)
 	}
+	return w.Write(p)
+}
+
+func (o *output) setFile(f *rotatingFile) error {
+	o.mu.Lock()
+	previous := o.file
+	o.file = f
+	o.mu.Unlock()
+
+	if previous != nil {
+		return previous.Close()
+	}
+	return nil
+}
+
+type componentHook string
+
+func (h componentHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
 
-	return l
+func (h componentHook) Fire(entry *logrus.Entry) error {
+	entry.Data["component"] = string(h)
+	return nil
 }
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggersWriteJSONWithComponent(t *testing.T) {
+	file := path.Join(t.TempDir(), "logs", "node.log")
+	loggers, err := New(Config{Format: FormatJSON, File: file, Quiet: true})
+	assert.NoError(t, err)
+
+	loggers.Logger(ComponentChain).WithField("height", 3).Info("Connected block")
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	line := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(string(b))), &line))
+	assert.Equal(t, "chain", line["component"])
+	assert.Equal(t, "Connected block", line["msg"])
+	assert.Equal(t, float64(3), line["height"])
+}
+
+func TestLoggersLevels(t *testing.T) {
+	loggers, err := New(Config{Level: "warn", Levels: map[string]string{ComponentP2P: "debug"}})
+	assert.NoError(t, err)
+	chain := loggers.Logger(ComponentChain)
+	p2p := loggers.Logger(ComponentP2P)
+
+	assert.Equal(t, logrus.WarnLevel, chain.GetLevel())
+	assert.Equal(t, logrus.DebugLevel, p2p.GetLevel())
+
+	assert.NoError(t, loggers.SetLevel(ComponentChain, "trace"))
+	assert.Equal(t, logrus.TraceLevel, chain.GetLevel())
+	assert.Equal(t, logrus.DebugLevel, p2p.GetLevel())
+
+	assert.NoError(t, loggers.SetLevel("", "error"))
+	assert.Equal(t, map[string]string{"chain": "error", "p2p": "error"}, loggers.Levels())
+	assert.Equal(t, logrus.ErrorLevel, loggers.Logger(ComponentRPC).GetLevel())
+
+	assert.Error(t, loggers.SetLevel(ComponentChain, "loud"))
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	_, err := New(Config{Format: "xml"})
+	assert.Error(t, err)
+	_, err = New(Config{Level: "loud"})
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	_, err = New(Config{File: dir})
+	assert.Error(t, err)
+}
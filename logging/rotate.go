@@ -0,0 +1,268 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const rotatedTimeFormat = "2006-01-02T15-04-05.000"
+
+// Rotation configures when the log file is rotated and how many rotated files are kept. Rotated files are
+// renamed <name>-<time>.<ext> in the directory of the log file, and gzipped when Compress is set.
+type Rotation struct {
+	// MaxSize rotates the file before it grows beyond that many bytes. Zero disables it.
+	MaxSize int64
+	// MaxAge rotates the file once it has been open for that long. Zero disables it.
+	MaxAge time.Duration
+	// MaxFiles is the number of rotated files kept, all of them when zero.
+	MaxFiles int
+	Compress bool
+}
+
+// rotatingFile is an append-only log file rotated by size and age. Compression and removal of old files
+// happen in the background so that writes are not held up.
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	rotation Rotation
+	file     *os.File
+	closed   bool
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+	millCh   chan struct{}
+	millWg   sync.WaitGroup
+}
+
+func openRotatingFile(path string, rotation Rotation) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, rotation: rotation, now: time.Now, millCh: make(chan struct{}, 1)}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	f.millWg.Add(1)
+	go f.mill()
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file != nil && f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to rotate log file: %v\n", err)
+		}
+	}
+	// a file that failed to open again after a rotation or a reopen is retried at every write
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// shouldRotate never rotates an empty file, so a single write larger than MaxSize still gets written.
+func (f *rotatingFile) shouldRotate(next int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.rotation.MaxSize > 0 && f.size+next > f.rotation.MaxSize {
+		return true
+	}
+	return f.rotation.MaxAge > 0 && f.now().Sub(f.openedAt) >= f.rotation.MaxAge
+}
+
+// rotate moves the file away and opens a new one. When the file cannot be moved, it is opened again to
+// keep logging to it.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	if err := os.Rename(f.path, f.rotatedName(f.now())); err != nil {
+		return errors.Join(err, f.open())
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	select {
+	case f.millCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Reopen closes and opens the file again, picking up a new file when the current one was moved away.
+func (f *rotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+	return f.open()
+}
+
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	f.closed = true
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	close(f.millCh)
+	f.millWg.Wait()
+	return err
+}
+
+func (f *rotatingFile) rotatedName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(f.path, ext), t.Format(rotatedTimeFormat), ext)
+}
+
+// rotated returns the rotated files, oldest first. The time in their names sorts them.
+func (f *rotatingFile) rotated() ([]string, error) {
+	ext := filepath.Ext(f.path)
+	matches, err := filepath.Glob(strings.TrimSuffix(f.path, ext) + "-*" + ext + "*")
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, match := range matches {
+		if strings.HasSuffix(match, ext) || strings.HasSuffix(match, ext+".gz") {
+			files = append(files, match)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (f *rotatingFile) mill() {
+	defer f.millWg.Done()
+	for range f.millCh {
+		f.millOnce()
+	}
+}
+
+// millOnce compresses the rotated files and removes the oldest ones beyond MaxFiles. Failures are reported
+// on stderr, as the log file is what failed.
+func (f *rotatingFile) millOnce() {
+	files, err := f.rotated()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to list rotated logs: %v\n", err)
+		return
+	}
+	if f.rotation.MaxFiles > 0 && len(files) > f.rotation.MaxFiles {
+		for _, file := range files[:len(files)-f.rotation.MaxFiles] {
+			if err := os.Remove(file); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to remove rotated log: %v\n", err)
+			}
+		}
+		files = files[len(files)-f.rotation.MaxFiles:]
+	}
+	if !f.rotation.Compress {
+		return
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, ".gz") {
+			continue
+		}
+		if err := compress(file); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to compress rotated log: %v\n", err)
+		}
+	}
+}
+
+// compress replaces path with path.gz.
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// ReopenOnSIGHUP reopens the log file whenever the process receives SIGHUP, for tools that move it away.
+// Calling stop ends it.
+func (l *Loggers) ReopenOnSIGHUP() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if err := l.Reopen(); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to reopen log file: %v\n", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
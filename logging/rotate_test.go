@@ -0,0 +1,148 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readGzip(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	b, err := io.ReadAll(gz)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestRotateBySizeKeepsMaxFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.log")
+	f, err := openRotatingFile(path, Rotation{MaxSize: 10, MaxFiles: 2, Compress: true})
+	assert.NoError(t, err)
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { clock = clock.Add(time.Second); return clock }
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, f.Close())
+	f.millOnce()
+
+	current, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(current))
+	rotated, err := f.rotated()
+	assert.NoError(t, err)
+	assert.Len(t, rotated, 2)
+	for _, file := range rotated {
+		assert.True(t, strings.HasSuffix(file, ".log.gz"))
+	}
+	assert.Equal(t, "second\n", readGzip(t, rotated[0]))
+	assert.Equal(t, "third\n", readGzip(t, rotated[1]))
+}
+
+func TestRotateByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.log")
+	f, err := openRotatingFile(path, Rotation{MaxAge: time.Hour})
+	assert.NoError(t, err)
+	defer f.Close()
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return clock }
+	f.openedAt = clock
+
+	f.Write([]byte("old\n"))
+	clock = clock.Add(30 * time.Minute)
+	f.Write([]byte("still old\n"))
+	clock = clock.Add(30 * time.Minute)
+	f.Write([]byte("new\n"))
+
+	rotated, err := f.rotated()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(filepath.Dir(path), "node-2024-01-01T01-00-00.000.log")}, rotated)
+	b, err := os.ReadFile(rotated[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "old\nstill old\n", string(b))
+}
+
+func TestRotateKeepsLoggingWhenRenameFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.log")
+	f, err := openRotatingFile(path, Rotation{MaxSize: 10})
+	assert.NoError(t, err)
+	defer f.Close()
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return clock }
+	// a directory in the way of the rotated file makes the rename fail
+	assert.NoError(t, os.Mkdir(f.rotatedName(clock), 0o755))
+
+	for _, line := range []string{"first\n", "second\n"} {
+		_, err := f.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(b))
+
+	clock = clock.Add(time.Second)
+	_, err = f.Write([]byte("third\n"))
+	assert.NoError(t, err)
+	b, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "third\n", string(b))
+}
+
+func TestWriteReopensAfterFailedReopen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "node.log")
+	f, err := openRotatingFile(path, Rotation{})
+	assert.NoError(t, err)
+	defer f.Close()
+
+	assert.NoError(t, os.RemoveAll(dir))
+	assert.Error(t, f.Reopen())
+	_, err = f.Write([]byte("lost\n"))
+	assert.Error(t, err)
+
+	assert.NoError(t, os.Mkdir(dir, 0o755))
+	_, err = f.Write([]byte("back\n"))
+	assert.NoError(t, err)
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "back\n", string(b))
+}
+
+func TestReopenOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "node.log")
+	loggers, err := New(Config{File: path, Quiet: true})
+	assert.NoError(t, err)
+	defer loggers.Close()
+	stop := loggers.ReopenOnSIGHUP()
+	defer stop()
+	logger := loggers.Logger(ComponentP2P)
+
+	logger.Info("before")
+	assert.NoError(t, os.Rename(path, filepath.Join(dir, "moved.log")))
+	process, err := os.FindProcess(os.Getpid())
+	assert.NoError(t, err)
+	assert.NoError(t, process.Signal(syscall.SIGHUP))
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	logger.Info("after")
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "after")
+	assert.NotContains(t, string(b), "before")
+}
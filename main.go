@@ -2,17 +2,28 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"strconv"
 	"time"
 
 	"github.com/fabrizioperria/blockchain/logging"
 	"github.com/fabrizioperria/blockchain/node"
 	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/sirupsen/logrus"
 	grpc "google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	n := []*node.Node{}
 	n = append(n, makeNode("localhost:3000", []string{}))
 	for i := 1; i < 30; i++ {
@@ -35,12 +46,21 @@ func main() {
 	// select {}
 }
 
-var log = logging.LoggerFactory("logs/log.log")
+var log = mustLogger()
+
+func mustLogger() *logrus.Logger {
+	loggers, err := logging.New(logging.Config{File: "logs/log.log"})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up logging: %v\n", err)
+		os.Exit(1)
+	}
+	return loggers.Logger("main")
+}
 
 func makeNode(listenAddr string, bootstrapNodes []string) *node.Node {
 	n := node.New()
 	go n.Start(listenAddr, bootstrapNodes)
-	time.Sleep(1 * time.Second)
+	<-n.Ready()
 
 	return n
 }
@@ -60,15 +80,6 @@ func makeTransaction(clientAddr string) {
 		Outputs: []*proto.TxOutput{},
 	}
 
-	_, err = nodeClient.Handshake(context.Background(), &proto.HandshakeMsg{
-		Version: "1.0.0",
-		Height:  100,
-		Address: ":4000",
-	})
-	if err != nil {
-		log.Fatalf("failed to make handshake: %v", err)
-	}
-
 	_, err = nodeClient.HandleTransaction(context.Background(), transaction)
 	if err != nil {
 		log.Fatalf("failed to make transaction: %v", err)
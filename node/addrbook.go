@@ -0,0 +1,342 @@
+package node
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mrand "math/rand"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	addrBookFile = "peers.json"
+
+	newBucketCount   = 64
+	triedBucketCount = 32
+	bucketSize       = 32
+
+	// A single source group can only ever fill this many new buckets, and a single address group
+	// this many tried buckets: an attacker controlling a few networks cannot take over the book.
+	newBucketsPerSourceGroup = 8
+	triedBucketsPerGroup     = 4
+
+	maxFailedAttempts = 5
+)
+
+type knownAddress struct {
+	Address     string    `json:"address"`
+	Source      string    `json:"source"`
+	LastAttempt time.Time `json:"lastAttempt"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	Attempts    int       `json:"attempts"`
+	Tried       bool      `json:"tried"`
+	bucket      int
+}
+
+func (ka *knownAddress) isBad() bool {
+	return !ka.Tried && ka.Attempts >= maxFailedAttempts
+}
+
+type addrBookFileData struct {
+	Key       string          `json:"key"`
+	Addresses []*knownAddress `json:"addresses"`
+}
+
+// AddrBook keeps track of the addresses of the network, split into new (heard of) and tried
+// (successfully connected to) buckets, and persists them so a restarted node can find its peers.
+type AddrBook struct {
+	mu           sync.Mutex
+	filePath     string
+	key          []byte
+	addresses    map[string]*knownAddress
+	newBuckets   [newBucketCount]map[string]*knownAddress
+	triedBuckets [triedBucketCount]map[string]*knownAddress
+	dirty        bool
+	clock        Clock
+}
+
+func NewAddrBook(dataDir string) *AddrBook {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+
+	b := &AddrBook{
+		filePath:  path.Join(dataDir, addrBookFile),
+		key:       key,
+		addresses: map[string]*knownAddress{},
+		clock:     systemClock{},
+	}
+	for i := range b.newBuckets {
+		b.newBuckets[i] = map[string]*knownAddress{}
+	}
+	for i := range b.triedBuckets {
+		b.triedBuckets[i] = map[string]*knownAddress{}
+	}
+
+	return b
+}
+
+func (b *AddrBook) Load() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	raw, err := os.ReadFile(b.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data := &addrBookFileData{}
+	if err := json.Unmarshal(raw, data); err != nil {
+		return fmt.Errorf("corrupted address book %s: %w", b.filePath, err)
+	}
+	key, err := hex.DecodeString(data.Key)
+	if err != nil || len(key) != len(b.key) {
+		return fmt.Errorf("corrupted address book %s: invalid key", b.filePath)
+	}
+	b.key = key
+
+	for _, ka := range data.Addresses {
+		if ka.Address == "" || b.addresses[ka.Address] != nil {
+			continue
+		}
+		if ka.Tried {
+			b.insertTried(ka)
+		} else {
+			b.insertNew(ka)
+		}
+	}
+
+	return nil
+}
+
+func (b *AddrBook) Save() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.dirty {
+		return nil
+	}
+
+	data := &addrBookFileData{
+		Key:       hex.EncodeToString(b.key),
+		Addresses: make([]*knownAddress, 0, len(b.addresses)),
+	}
+	for _, ka := range b.addresses {
+		data.Addresses = append(data.Addresses, ka)
+	}
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(path.Dir(b.filePath), 0o755); err != nil {
+		return err
+	}
+	tmp := b.filePath + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, b.filePath); err != nil {
+		return err
+	}
+	b.dirty = false
+
+	return nil
+}
+
+func (b *AddrBook) Size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.addresses)
+}
+
+// AddAddress records an address we heard of from source. Addresses we already know are left untouched.
+func (b *AddrBook) AddAddress(address string, source string) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.addresses[address]; ok {
+		return
+	}
+	b.insertNew(&knownAddress{Address: address, Source: source})
+	b.dirty = true
+}
+
+func (b *AddrBook) MarkAttempt(address string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ka, ok := b.addresses[address]
+	if !ok {
+		return
+	}
+	ka.LastAttempt = b.clock.Now()
+	ka.Attempts++
+	b.dirty = true
+}
+
+// MarkGood records a successful connection to address, promoting it to the tried buckets.
+func (b *AddrBook) MarkGood(address string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ka, ok := b.addresses[address]
+	if !ok {
+		ka = &knownAddress{Address: address, Source: address}
+	}
+	now := b.clock.Now()
+	ka.LastAttempt = now
+	ka.LastSuccess = now
+	ka.Attempts = 0
+	b.dirty = true
+
+	if ka.Tried {
+		return
+	}
+	if ok {
+		b.remove(ka)
+	}
+	b.insertTried(ka)
+}
+
+// Select returns up to count addresses picked at random, half of the time from the tried buckets and
+// half of the time from the new ones. Addresses for which skip returns true are never returned.
+func (b *AddrBook) Select(count int, skip func(string) bool) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var tried, fresh []*knownAddress
+	for _, ka := range b.addresses {
+		if ka.isBad() || skip(ka.Address) {
+			continue
+		}
+		if ka.Tried {
+			tried = append(tried, ka)
+		} else {
+			fresh = append(fresh, ka)
+		}
+	}
+	mrand.Shuffle(len(tried), func(i, j int) { tried[i], tried[j] = tried[j], tried[i] })
+	mrand.Shuffle(len(fresh), func(i, j int) { fresh[i], fresh[j] = fresh[j], fresh[i] })
+
+	selected := []string{}
+	for len(selected) < count && len(tried)+len(fresh) > 0 {
+		if len(fresh) == 0 || (len(tried) > 0 && mrand.Intn(2) == 0) {
+			selected = append(selected, tried[0].Address)
+			tried = tried[1:]
+		} else {
+			selected = append(selected, fresh[0].Address)
+			fresh = fresh[1:]
+		}
+	}
+
+	return selected
+}
+
+func (b *AddrBook) insertNew(ka *knownAddress) {
+	ka.Tried = false
+	ka.bucket = b.newBucket(ka.Address, ka.Source)
+	bucket := b.newBuckets[ka.bucket]
+	if len(bucket) >= bucketSize {
+		b.remove(worstAddress(bucket))
+	}
+	bucket[ka.Address] = ka
+	b.addresses[ka.Address] = ka
+}
+
+func (b *AddrBook) insertTried(ka *knownAddress) {
+	ka.Tried = true
+	ka.bucket = b.triedBucket(ka.Address)
+	bucket := b.triedBuckets[ka.bucket]
+	if len(bucket) >= bucketSize {
+		// make room by sending the least recently successful address back to the new buckets
+		oldest := oldestSuccess(bucket)
+		b.remove(oldest)
+		b.insertNew(oldest)
+	}
+	bucket[ka.Address] = ka
+	b.addresses[ka.Address] = ka
+}
+
+func (b *AddrBook) remove(ka *knownAddress) {
+	if ka.Tried {
+		delete(b.triedBuckets[ka.bucket], ka.Address)
+	} else {
+		delete(b.newBuckets[ka.bucket], ka.Address)
+	}
+	delete(b.addresses, ka.Address)
+}
+
+func (b *AddrBook) newBucket(address string, source string) int {
+	sourceGroup := addressGroup(source)
+	perSource := b.hash([]byte(addressGroup(address)), []byte(sourceGroup)) % newBucketsPerSourceGroup
+	return int(b.hash([]byte(sourceGroup), binary.BigEndian.AppendUint64(nil, perSource)) % newBucketCount)
+}
+
+func (b *AddrBook) triedBucket(address string) int {
+	perGroup := b.hash([]byte(address)) % triedBucketsPerGroup
+	return int(b.hash([]byte(addressGroup(address)), binary.BigEndian.AppendUint64(nil, perGroup)) % triedBucketCount)
+}
+
+func (b *AddrBook) hash(parts ...[]byte) uint64 {
+	h := sha256.New()
+	h.Write(b.key)
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return binary.BigEndian.Uint64(h.Sum(nil))
+}
+
+// addressGroup maps an address to the network it belongs to: /16 for IPv4, /32 for IPv6 and
+// the host itself for names.
+func addressGroup(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(16, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(32, 128)).String()
+}
+
+func worstAddress(bucket map[string]*knownAddress) *knownAddress {
+	var worst *knownAddress
+	for _, ka := range bucket {
+		if worst == nil || ka.Attempts > worst.Attempts ||
+			(ka.Attempts == worst.Attempts && ka.LastAttempt.Before(worst.LastAttempt)) {
+			worst = ka
+		}
+	}
+	return worst
+}
+
+func oldestSuccess(bucket map[string]*knownAddress) *knownAddress {
+	var oldest *knownAddress
+	for _, ka := range bucket {
+		if oldest == nil || ka.LastSuccess.Before(oldest.LastSuccess) {
+			oldest = ka
+		}
+	}
+	return oldest
+}
@@ -0,0 +1,98 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddrBookAddAndSelect(t *testing.T) {
+	b := NewAddrBook(t.TempDir())
+	b.AddAddress("10.0.0.1:3000", "10.0.0.2:3000")
+	b.AddAddress("10.0.0.3:3000", "10.0.0.2:3000")
+	b.AddAddress("10.0.0.1:3000", "10.0.0.4:3000")
+	b.AddAddress("not an address", "10.0.0.2:3000")
+	assert.Equal(t, 2, b.Size())
+
+	selected := b.Select(10, func(string) bool { return false })
+	assert.ElementsMatch(t, []string{"10.0.0.1:3000", "10.0.0.3:3000"}, selected)
+
+	selected = b.Select(10, func(address string) bool { return address == "10.0.0.1:3000" })
+	assert.Equal(t, []string{"10.0.0.3:3000"}, selected)
+
+	assert.Len(t, b.Select(1, func(string) bool { return false }), 1)
+}
+
+func TestAddrBookMarkGoodMovesToTried(t *testing.T) {
+	b := NewAddrBook(t.TempDir())
+	b.AddAddress("10.0.0.1:3000", "10.0.0.2:3000")
+	b.MarkAttempt("10.0.0.1:3000")
+	b.MarkGood("10.0.0.1:3000")
+
+	ka := b.addresses["10.0.0.1:3000"]
+	assert.True(t, ka.Tried)
+	assert.Zero(t, ka.Attempts)
+	assert.False(t, ka.LastSuccess.IsZero())
+	assert.Contains(t, b.triedBuckets[ka.bucket], "10.0.0.1:3000")
+	for _, bucket := range b.newBuckets {
+		assert.NotContains(t, bucket, "10.0.0.1:3000")
+	}
+}
+
+func TestAddrBookSkipsBadAddresses(t *testing.T) {
+	b := NewAddrBook(t.TempDir())
+	b.AddAddress("10.0.0.1:3000", "10.0.0.2:3000")
+	for i := 0; i < maxFailedAttempts; i++ {
+		b.MarkAttempt("10.0.0.1:3000")
+	}
+
+	assert.Empty(t, b.Select(10, func(string) bool { return false }))
+}
+
+func TestAddrBookPersistence(t *testing.T) {
+	dir := t.TempDir()
+	b := NewAddrBook(dir)
+	b.AddAddress("10.0.0.1:3000", "10.0.0.2:3000")
+	b.AddAddress("10.0.0.3:3000", "10.0.0.2:3000")
+	b.MarkGood("10.0.0.3:3000")
+	assert.NoError(t, b.Save())
+
+	loaded := NewAddrBook(dir)
+	assert.NoError(t, loaded.Load())
+	assert.Equal(t, b.key, loaded.key)
+	assert.Equal(t, 2, loaded.Size())
+	assert.False(t, loaded.addresses["10.0.0.1:3000"].Tried)
+	assert.True(t, loaded.addresses["10.0.0.3:3000"].Tried)
+	assert.Equal(t, b.addresses["10.0.0.1:3000"].bucket, loaded.addresses["10.0.0.1:3000"].bucket)
+}
+
+func TestAddrBookLimitsSingleSource(t *testing.T) {
+	b := NewAddrBook(t.TempDir())
+	for i := 0; i < 1000; i++ {
+		b.AddAddress(fmt.Sprintf("%d.%d.0.1:3000", i/250+1, i%250), "66.66.66.66:3000")
+	}
+
+	used := 0
+	for _, bucket := range b.newBuckets {
+		if len(bucket) > 0 {
+			used++
+		}
+	}
+	assert.LessOrEqual(t, used, newBucketsPerSourceGroup)
+	assert.LessOrEqual(t, b.Size(), newBucketsPerSourceGroup*bucketSize)
+}
+
+func FuzzAddrBookAddAddress(f *testing.F) {
+	f.Add("10.0.0.1:3000", "10.0.0.2:3000")
+	f.Add("[::1]:3000", "localhost:3000")
+	f.Add("not an address", "")
+
+	f.Fuzz(func(t *testing.T, address string, source string) {
+		b := NewAddrBook(t.TempDir())
+		b.AddAddress(address, source)
+		b.MarkAttempt(address)
+		b.MarkGood(address)
+		assert.LessOrEqual(t, len(b.Select(1, func(string) bool { return false })), b.Size())
+	})
+}
@@ -0,0 +1,149 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sync"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+)
+
+// AddrIndex records, for every address, the main chain transactions crediting it through TxOutput.DestAddress
+// or debiting it through an input signed by its key.
+type AddrIndex struct {
+	mu      sync.RWMutex
+	history map[string][]*proto.AddressHistoryEntry
+	height  int32
+}
+
+func NewAddrIndex() *AddrIndex {
+	return &AddrIndex{history: map[string][]*proto.AddressHistoryEntry{}, height: -1}
+}
+
+func inputAddress(input *proto.TxInput) []byte {
+	publicKey, err := crypto.PublicKeyFromBytes(input.PublicKey)
+	if err != nil {
+		return nil
+	}
+	return publicKey.Address().Bytes()
+}
+
+// ConnectBlock debits the inputs of block from the outputs it spent from the chain, given by spent, or from the
+// outputs of the transactions before them in block, which spent leaves out.
+func (ix *AddrIndex) ConnectBlock(block *proto.Block, height int32, spent []*UTXO) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	amounts := map[string]int64{}
+	for _, utxo := range spent {
+		amounts[outpointKey(utxo.TxHash, utxo.OutputIndex)] = utxo.Output.Amount
+	}
+
+	blockHash := types.HashBlockSHA256(block)
+	for i, tx := range block.Transaction {
+		txHash := types.HashTransactionSHA256(tx)
+		entries := map[string]*proto.AddressHistoryEntry{}
+		entry := func(address []byte) *proto.AddressHistoryEntry {
+			key := hex.EncodeToString(address)
+			if _, ok := entries[key]; !ok {
+				entries[key] = &proto.AddressHistoryEntry{
+					TxHash:    txHash,
+					BlockHash: blockHash,
+					Height:    height,
+					Index:     int32(i),
+				}
+				ix.history[key] = append(ix.history[key], entries[key])
+			}
+			return entries[key]
+		}
+
+		for _, input := range tx.Inputs {
+			if address := inputAddress(input); address != nil {
+				entry(address).Debit += amounts[outpointKey(input.PreviousTxHash, input.PrevOutputIndex)]
+			}
+		}
+		for j, output := range tx.Outputs {
+			entry(output.DestAddress).Credit += output.Amount
+			amounts[outpointKey(txHash, int32(j))] = output.Amount
+		}
+	}
+	ix.height = height
+
+	return nil
+}
+
+func (ix *AddrIndex) DisconnectBlock(block *proto.Block, height int32, _ []*UTXO) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	blockHash := types.HashBlockSHA256(block)
+	addresses := map[string]bool{}
+	for _, tx := range block.Transaction {
+		for _, input := range tx.Inputs {
+			if address := inputAddress(input); address != nil {
+				addresses[hex.EncodeToString(address)] = true
+			}
+		}
+		for _, output := range tx.Outputs {
+			addresses[hex.EncodeToString(output.DestAddress)] = true
+		}
+	}
+
+	for key := range addresses {
+		history := ix.history[key]
+		for len(history) > 0 && bytes.Equal(history[len(history)-1].BlockHash, blockHash) {
+			history = history[:len(history)-1]
+		}
+		if len(history) == 0 {
+			delete(ix.history, key)
+		} else {
+			ix.history[key] = history
+		}
+	}
+	ix.height = height - 1
+
+	return nil
+}
+
+func (ix *AddrIndex) Height() int32 {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return ix.height
+}
+
+func (ix *AddrIndex) Reset() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.history = map[string][]*proto.AddressHistoryEntry{}
+	ix.height = -1
+}
+
+// History returns the entries of address, newest first, skipping the first offset ones.
+func (ix *AddrIndex) History(address []byte, offset int, limit int) (entries []*proto.AddressHistoryEntry, total int) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	history := ix.history[hex.EncodeToString(address)]
+	for i := len(history) - 1 - offset; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, history[i])
+	}
+	return entries, len(history)
+}
+
+func (ix *AddrIndex) BalanceAtHeight(address []byte, height int32) int64 {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	balance := int64(0)
+	for _, entry := range ix.history[hex.EncodeToString(address)] {
+		if entry.Height > height {
+			break
+		}
+		balance += entry.Credit - entry.Debit
+	}
+	return balance
+}
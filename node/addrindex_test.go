@@ -0,0 +1,120 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAddrIndexHistory(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	assert.NoError(t, c.EnableAddrIndex())
+	alice := crypto.GeneratePrivateKey()
+	bob := crypto.GeneratePrivateKey().Public().Address()
+	mint := utils.MintTransaction(alice.Public().Address(), 100)
+	addTestBlock(t, c, mint)
+	payment := utils.SpendTransaction(alice, mint, 0,
+		&proto.TxOutput{Amount: 30, DestAddress: bob.Bytes()},
+		&proto.TxOutput{Amount: 70, DestAddress: alice.Public().Address().Bytes()},
+	)
+	addTestBlock(t, c, payment)
+
+	entries, total := c.addrIndex.History(alice.Public().Address().Bytes(), 0, 10)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, types.HashTransactionSHA256(payment), entries[0].TxHash)
+	assert.Equal(t, int64(70), entries[0].Credit)
+	assert.Equal(t, int64(100), entries[0].Debit)
+	assert.Equal(t, int32(2), entries[0].Height)
+	assert.Equal(t, types.HashTransactionSHA256(mint), entries[1].TxHash)
+	assert.Equal(t, int64(100), entries[1].Credit)
+
+	assert.Equal(t, int64(100), c.addrIndex.BalanceAtHeight(alice.Public().Address().Bytes(), 1))
+	assert.Equal(t, int64(70), c.addrIndex.BalanceAtHeight(alice.Public().Address().Bytes(), 2))
+	assert.Zero(t, c.addrIndex.BalanceAtHeight(bob.Bytes(), 1))
+	assert.Equal(t, int64(30), c.addrIndex.BalanceAtHeight(bob.Bytes(), 2))
+}
+
+func TestAddrIndexDebitsSpendsWithinABlock(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	alice, bob := crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	addTestBlock(t, c, mint)
+	toBob := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 10, DestAddress: bob.Public().Address().Bytes()})
+	toAlice := utils.SpendTransaction(bob, toBob, 0, &proto.TxOutput{Amount: 10, DestAddress: alice.Public().Address().Bytes()})
+	live := NewAddrIndex()
+	assert.NoError(t, c.AddIndexer(live))
+	addTestBlock(t, c, toBob, toAlice)
+
+	// the index built block by block and the one rebuilt from the undo data agree
+	rebuilt := NewAddrIndex()
+	assert.NoError(t, c.AddIndexer(rebuilt))
+	for _, ix := range []*AddrIndex{live, rebuilt} {
+		assert.Zero(t, ix.BalanceAtHeight(bob.Public().Address().Bytes(), 2))
+		assert.Equal(t, int64(10), ix.BalanceAtHeight(alice.Public().Address().Bytes(), 2))
+		entries, _ := ix.History(bob.Public().Address().Bytes(), 0, 10)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, int64(10), entries[0].Debit)
+		assert.Equal(t, int64(10), entries[1].Credit)
+	}
+	assert.Zero(t, c.utxos.Balance(bob.Public().Address().Bytes()))
+}
+
+func TestAddrIndexFollowsReorgs(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	alice := crypto.GeneratePrivateKey().Public().Address()
+	base := addTestBlock(t, c, utils.MintTransaction(alice, 10))
+	addTestBlock(t, c, utils.MintTransaction(alice, 5))
+	assert.NoError(t, c.EnableAddrIndex())
+	_, total := c.addrIndex.History(alice.Bytes(), 0, 10)
+	assert.Equal(t, 2, total)
+
+	side := utils.ChildBlock(t, base)
+	assert.NoError(t, c.AddBlock(side))
+	assert.NoError(t, c.AddBlock(utils.ChildBlock(t, side)))
+
+	entries, total := c.addrIndex.History(alice.Bytes(), 0, 10)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, int64(10), entries[0].Credit)
+	assert.Equal(t, int32(3), c.addrIndex.Height())
+}
+
+func TestQueryAddressHistory(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	q := &queryServer{chain: c}
+	alice := crypto.GeneratePrivateKey().Public().Address()
+	ctx := context.Background()
+
+	_, err := q.GetAddressHistory(ctx, &proto.AddressRequest{Address: alice.Bytes()})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	assert.NoError(t, c.EnableAddrIndex())
+	for i := 0; i < 5; i++ {
+		addTestBlock(t, c, utils.MintTransaction(alice, int64(i+1)))
+	}
+
+	page, err := q.GetAddressHistory(ctx, &proto.AddressRequest{Address: alice.Bytes(), PageSize: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), page.Total)
+	assert.Len(t, page.Entries, 3)
+	assert.Equal(t, int64(5), page.Entries[0].Credit)
+	assert.Equal(t, "3", page.NextPageToken)
+
+	page, err = q.GetAddressHistory(ctx, &proto.AddressRequest{Address: alice.Bytes(), PageSize: 3, PageToken: page.NextPageToken})
+	assert.NoError(t, err)
+	assert.Len(t, page.Entries, 2)
+	assert.Equal(t, int64(1), page.Entries[1].Credit)
+	assert.Empty(t, page.NextPageToken)
+
+	balance, err := q.GetBalanceAtHeight(ctx, &proto.BalanceRequest{Address: alice.Bytes(), Height: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), balance.Amount)
+	_, err = q.GetBalanceAtHeight(ctx, &proto.BalanceRequest{Address: alice.Bytes(), Height: 6})
+	assert.Equal(t, codes.OutOfRange, status.Code(err))
+}
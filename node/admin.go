@@ -0,0 +1,281 @@
+package node
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const adminServicePrefix = "/Admin/"
+
+type adminServer struct {
+	proto.UnimplementedAdminServer
+	node *Node
+}
+
+func (a *adminServer) GetNodeInfo(ctx context.Context, _ *proto.Ack) (*proto.NodeInfo, error) {
+	n := a.node
+	info := &proto.NodeInfo{
+		Version:       n.version,
+		MinVersion:    MinProtocolVersion,
+		Identity:      n.id,
+		ListenAddress: n.listenAddr,
+		Services:      uint64(n.config.Services),
+		Height:        n.chain.Height(),
+		Syncing:       n.syncing.Load(),
+		UptimeSeconds: int64(n.clock.Now().Sub(n.startedAt).Seconds()),
+	}
+	if tip, err := n.chain.GetBlockByHeight(info.Height); err == nil {
+		info.TipHash = types.HashBlockSHA256(tip)
+	}
+	if best := n.bestPeer(); best != nil {
+		info.BestPeerHeight = best.data.Height
+	}
+	n.peers.Range(func(_, value interface{}) bool {
+		if value.(*addPeerData).inbound {
+			info.InboundPeers++
+		} else {
+			info.OutboundPeers++
+		}
+		return true
+	})
+
+	return info, nil
+}
+
+func (a *adminServer) GetPeerInfo(ctx context.Context, _ *proto.Ack) (*proto.PeerInfoList, error) {
+	list := &proto.PeerInfoList{}
+	a.node.peers.Range(func(_, value interface{}) bool {
+		list.Peers = append(list.Peers, value.(*addPeerData).info())
+		return true
+	})
+	return list, nil
+}
+
+func (a *adminServer) AddPeer(ctx context.Context, target *proto.PeerTarget) (*proto.Ack, error) {
+	n := a.node
+	if n.hasConnectedTo(target.Target) {
+		return nil, status.Errorf(codes.AlreadyExists, "already connected to %s", target.Target)
+	}
+	n.addrBook.AddAddress(target.Target, n.listenAddr)
+	client, msg, err := n.dialRemote(target.Target)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to connect to %s: %v", target.Target, err)
+	}
+	n.addPeer(client, msg, false)
+	return &proto.Ack{}, nil
+}
+
+func (a *adminServer) RemovePeer(ctx context.Context, target *proto.PeerTarget) (*proto.Ack, error) {
+	peer := a.node.findPeer(target.Target)
+	if peer == nil {
+		return nil, status.Errorf(codes.NotFound, "peer %s not found", target.Target)
+	}
+	a.node.removePeer(peer.id())
+	return &proto.Ack{}, nil
+}
+
+func (a *adminServer) BanPeer(ctx context.Context, req *proto.BanRequest) (*proto.Ack, error) {
+	if req.Target == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing ban target")
+	}
+	duration := time.Duration(req.DurationSeconds) * time.Second
+	if duration <= 0 {
+		duration = defaultBanHours * time.Hour
+	}
+
+	n := a.node
+	n.bans.ban(req.Target, n.clock.Now().Add(duration))
+	n.peers.Range(func(_, value interface{}) bool {
+		if peer := value.(*addPeerData); n.bans.isBanned(peer.data.Address, peer.id()) {
+			n.removePeer(peer.id())
+		}
+		return true
+	})
+	n.logger.WithFields(logrus.Fields{
+		"target":   req.Target,
+		"duration": duration,
+	}).Info("Banned peer")
+
+	return &proto.Ack{}, nil
+}
+
+func (a *adminServer) UnbanPeer(ctx context.Context, target *proto.PeerTarget) (*proto.Ack, error) {
+	if !a.node.bans.unban(target.Target) {
+		return nil, status.Errorf(codes.NotFound, "%s is not banned", target.Target)
+	}
+	return &proto.Ack{}, nil
+}
+
+func (a *adminServer) ListBans(ctx context.Context, _ *proto.Ack) (*proto.BanList, error) {
+	list := &proto.BanList{}
+	for _, b := range a.node.bans.list() {
+		list.Bans = append(list.Bans, &proto.Ban{Target: b.target, Until: b.until.Unix()})
+	}
+	return list, nil
+}
+
+func (a *adminServer) Resync(ctx context.Context, _ *proto.Ack) (*proto.Ack, error) {
+	a.node.requestSync()
+	return &proto.Ack{}, nil
+}
+
+func (a *adminServer) GetLogLevels(ctx context.Context, _ *proto.Ack) (*proto.LogLevels, error) {
+	return a.logLevels(), nil
+}
+
+// SetLogLevel changes the level of a component logger, or of all of them when no component is given.
+func (a *adminServer) SetLogLevel(ctx context.Context, req *proto.LogLevel) (*proto.LogLevels, error) {
+	if _, ok := a.node.loggers.Levels()[req.Component]; req.Component != "" && !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown log component %q", req.Component)
+	}
+	if err := a.node.loggers.SetLevel(req.Component, req.Level); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	a.node.logger.WithFields(logrus.Fields{
+		"component": req.Component,
+		"level":     req.Level,
+	}).Info("Changed log level")
+	return a.logLevels(), nil
+}
+
+// ExportChain writes a range of blocks of the main chain into a chain file, on the filesystem of the node.
+func (a *adminServer) ExportChain(ctx context.Context, req *proto.ChainExportRequest) (*proto.ChainFileInfo, error) {
+	if req.Path == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing chain file path")
+	}
+	n := a.node
+	to := req.ToHeight
+	if to == 0 {
+		to = n.chain.Height()
+	}
+	if req.FromHeight < 0 || to < req.FromHeight || to > n.chain.Height() {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid block range %d-%d", req.FromHeight, to)
+	}
+
+	header, err := n.chain.exportFile(req.Path, req.FromHeight, to)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to export the chain: %v", err)
+	}
+	n.chainLogger.WithFields(logrus.Fields{
+		"path": req.Path,
+		"from": header.From,
+		"to":   header.To,
+	}).Info("Exported chain")
+	info := header.info()
+	info.Height = n.chain.Height()
+	return info, nil
+}
+
+// ImportChain adds the blocks of a chain file, on the filesystem of the node, to the chain. The blocks before
+// an invalid one stay in the chain, and the error tells up to which height they were imported.
+func (a *adminServer) ImportChain(ctx context.Context, req *proto.ChainImportRequest) (*proto.ChainFileInfo, error) {
+	if req.Path == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing chain file path")
+	}
+	n := a.node
+	header, imported, err := n.chain.importFile(req.Path, req.TrustedHeight)
+	var validationErr *ValidationError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, status.Errorf(codes.NotFound, "chain file %s not found", req.Path)
+	case errors.Is(err, ErrInvalidChainFile), errors.Is(err, ErrUnknownParent), errors.As(err, &validationErr):
+		return nil, status.Errorf(codes.InvalidArgument, "failed to import the chain: %v (%s)", err, importedUpTo(imported))
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to import the chain: %v (%s)", err, importedUpTo(imported))
+	}
+	n.chainLogger.WithFields(logrus.Fields{
+		"path":          req.Path,
+		"from":          header.From,
+		"to":            header.To,
+		"trustedHeight": req.TrustedHeight,
+	}).Info("Imported chain")
+	info := header.info()
+	info.Height = n.chain.Height()
+	return info, nil
+}
+
+// importedUpTo tells how far a failed import went, given the height of the last block it added.
+func importedUpTo(height int32) string {
+	if height < 0 {
+		return "no block imported"
+	}
+	return fmt.Sprintf("blocks imported up to height %d", height)
+}
+
+func (a *adminServer) logLevels() *proto.LogLevels {
+	levels := a.node.loggers.Levels()
+	list := &proto.LogLevels{}
+	for component, level := range levels {
+		list.Levels = append(list.Levels, &proto.LogLevel{Component: component, Level: level})
+	}
+	sort.Slice(list.Levels, func(i, j int) bool { return list.Levels[i].Component < list.Levels[j].Component })
+	return list
+}
+
+// authorizeAdmin lets admin calls through only from localhost or, when a token is configured, with
+// an "authorization: Bearer <token>" header.
+func (n *Node) authorizeAdmin(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, adminServicePrefix) {
+		return handler(ctx, req)
+	}
+	if isLocalCall(ctx) || hasAdminToken(ctx, n.config.AdminToken) {
+		return handler(ctx, req)
+	}
+	return nil, status.Error(codes.PermissionDenied, "admin API is restricted to localhost or token holders")
+}
+
+func isLocalCall(ctx context.Context) bool {
+	ip := net.ParseIP(remoteHost(ctx))
+	return ip != nil && ip.IsLoopback()
+}
+
+// remoteAddress returns the address the call comes from, empty when unknown.
+func remoteAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	return p.Addr.String()
+}
+
+// remoteHost returns the host the call comes from, empty when unknown.
+func remoteHost(ctx context.Context) string {
+	host, _, err := net.SplitHostPort(remoteAddress(ctx))
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+func hasAdminToken(ctx context.Context, token string) bool {
+	if token == "" {
+		return false
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	expected := []byte("Bearer " + token)
+	for _, value := range md.Get("authorization") {
+		if subtle.ConstantTimeCompare([]byte(value), expected) == 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package node
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fabrizioperria/blockchain/logging"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func callAdmin(n *Node, ctx context.Context, method string) error {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return &proto.Ack{}, nil }
+	_, err := n.authorizeAdmin(ctx, &proto.Ack{}, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	return err
+}
+
+func TestAuthorizeAdmin(t *testing.T) {
+	n := NewWithConfig(&Config{AdminToken: "secret"})
+	local := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv6loopback, Port: 4000}})
+	remote := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4000}})
+
+	assert.NoError(t, callAdmin(n, local, "/Admin/GetNodeInfo"))
+	assert.NoError(t, callAdmin(n, remote, "/Node/Ping"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(callAdmin(n, remote, "/Admin/GetNodeInfo")))
+
+	withToken := metadata.NewIncomingContext(remote, metadata.Pairs("authorization", "Bearer secret"))
+	assert.NoError(t, callAdmin(n, withToken, "/Admin/GetNodeInfo"))
+	withWrongToken := metadata.NewIncomingContext(remote, metadata.Pairs("authorization", "Bearer nope"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(callAdmin(n, withWrongToken, "/Admin/BanPeer")))
+
+	n.config.AdminToken = ""
+	empty := metadata.NewIncomingContext(remote, metadata.Pairs("authorization", "Bearer "))
+	assert.Equal(t, codes.PermissionDenied, status.Code(callAdmin(n, empty, "/Admin/GetNodeInfo")))
+}
+
+func TestBanList(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	b := newBanList(clock)
+	b.ban("10.0.0.1:3000", clock.Now().Add(time.Hour))
+	b.ban("abcdef", clock.Now().Add(time.Hour))
+	b.ban("10.0.0.2:3000", clock.Now().Add(-time.Hour))
+
+	assert.True(t, b.isBanned("10.0.0.1:4000", ""))
+	assert.True(t, b.isBanned("10.0.0.3:3000", "abcdef"))
+	assert.False(t, b.isBanned("10.0.0.2:3000", ""))
+	assert.Len(t, b.list(), 2)
+
+	assert.True(t, b.unban("10.0.0.1:3000"))
+	assert.False(t, b.unban("10.0.0.1:3000"))
+	assert.False(t, b.isBanned("10.0.0.1:4000", ""))
+}
+
+func TestBanExpires(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	b := newBanList(clock)
+	b.ban("10.0.0.1:3000", clock.Now().Add(time.Hour))
+
+	clock.Advance(time.Hour - time.Second)
+	assert.True(t, b.isBanned("10.0.0.1:3000", ""))
+	clock.Advance(time.Second)
+	assert.False(t, b.isBanned("10.0.0.1:3000", ""))
+	assert.Empty(t, b.list())
+}
+
+func TestAdminService(t *testing.T) {
+	a := NewWithConfig(&Config{DataDir: t.TempDir(), MaxPeers: 8, Services: ServiceFullNode})
+	addTestBlocks(t, a.chain, 5)
+	makeNodeWithInstance(a, "localhost:3100", []string{})
+	b := NewWithConfig(&Config{DataDir: t.TempDir(), MaxPeers: 8, Services: ServiceFullNode})
+	makeNodeWithInstance(b, "localhost:3101", []string{"localhost:3100"})
+
+	assert.Eventually(t, func() bool { return b.chain.Height() == 5 }, 5*time.Second, 10*time.Millisecond)
+
+	conn, err := grpc.NewClient("localhost:3101", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+	admin := proto.NewAdminClient(conn)
+	ctx := context.Background()
+
+	info, err := admin.GetNodeInfo(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+	assert.Equal(t, ProtocolVersion, info.Version)
+	assert.Equal(t, b.id, info.Identity)
+	assert.Equal(t, int32(5), info.Height)
+	assert.Equal(t, int32(1), info.OutboundPeers)
+	tip, err := b.chain.GetBlockByHeight(5)
+	assert.NoError(t, err)
+	assert.Equal(t, types.HashBlockSHA256(tip), info.TipHash)
+
+	peers, err := admin.GetPeerInfo(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+	assert.Len(t, peers.Peers, 1)
+	assert.Equal(t, a.id, peers.Peers[0].Identity)
+	assert.Equal(t, "localhost:3100", peers.Peers[0].Address)
+	assert.False(t, peers.Peers[0].Inbound)
+	assert.NotZero(t, peers.Peers[0].BytesReceived)
+
+	_, err = admin.BanPeer(ctx, &proto.BanRequest{Target: a.id, DurationSeconds: 60})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(b.GetPeers()) == 0 }, time.Second, 10*time.Millisecond)
+	bans, err := admin.ListBans(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+	assert.Len(t, bans.Bans, 1)
+
+	_, err = admin.AddPeer(ctx, &proto.PeerTarget{Target: "localhost:3100"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	_, err = admin.UnbanPeer(ctx, &proto.PeerTarget{Target: a.id})
+	assert.NoError(t, err)
+	_, err = admin.RemovePeer(ctx, &proto.PeerTarget{Target: "localhost:3100"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = admin.Resync(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+}
+
+func TestAdminLogLevels(t *testing.T) {
+	n := NewWithConfig(&Config{Log: logging.Config{Levels: map[string]string{logging.ComponentChain: "debug"}}})
+	a := &adminServer{node: n}
+	ctx := context.Background()
+
+	levels, err := a.GetLogLevels(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+	assert.Len(t, levels.Levels, 4)
+	assert.Equal(t, &proto.LogLevel{Component: "chain", Level: "debug"}, levels.Levels[0])
+
+	_, err = a.SetLogLevel(ctx, &proto.LogLevel{Component: logging.ComponentP2P, Level: "warn"})
+	assert.NoError(t, err)
+	assert.Equal(t, logrus.WarnLevel, n.logger.GetLevel())
+	assert.Equal(t, logrus.DebugLevel, n.chainLogger.GetLevel())
+
+	_, err = a.SetLogLevel(ctx, &proto.LogLevel{Level: "error"})
+	assert.NoError(t, err)
+	assert.Equal(t, logrus.ErrorLevel, n.chainLogger.GetLevel())
+
+	_, err = a.SetLogLevel(ctx, &proto.LogLevel{Component: "consensus", Level: "warn"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = a.SetLogLevel(ctx, &proto.LogLevel{Component: logging.ComponentRPC, Level: "loud"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestInvalidLogConfigFallsBack(t *testing.T) {
+	n := NewWithConfig(&Config{Log: logging.Config{Format: "xml", Levels: map[string]string{logging.ComponentChain: "debug"}}})
+	assert.NotNil(t, n.logger)
+	assert.Equal(t, logrus.InfoLevel, n.chainLogger.GetLevel())
+}
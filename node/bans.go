@@ -0,0 +1,84 @@
+package node
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultBanHours = 24
+
+// banList holds the peers we refuse to talk to. A ban targets either an identity or a host, so that
+// a banned node cannot come back just by changing port.
+type banList struct {
+	mu    sync.Mutex
+	clock Clock
+	bans  map[string]time.Time
+}
+
+func newBanList(clock Clock) *banList {
+	return &banList{clock: clock, bans: map[string]time.Time{}}
+}
+
+func banTarget(target string) string {
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return host
+	}
+	return target
+}
+
+func (b *banList) ban(target string, until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bans[banTarget(target)] = until
+}
+
+func (b *banList) unban(target string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	target = banTarget(target)
+	_, ok := b.bans[target]
+	delete(b.bans, target)
+	return ok
+}
+
+func (b *banList) isBanned(address string, id string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	for _, target := range []string{banTarget(address), id} {
+		until, ok := b.bans[target]
+		if !ok {
+			continue
+		}
+		if now.Before(until) {
+			return true
+		}
+		delete(b.bans, target)
+	}
+	return false
+}
+
+type ban struct {
+	target string
+	until  time.Time
+}
+
+func (b *banList) list() []ban {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	bans := []ban{}
+	for target, until := range b.bans {
+		if now.Before(until) {
+			bans = append(bans, ban{target: target, until: until})
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].target < bans[j].target })
+	return bans
+}
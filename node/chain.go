@@ -1,8 +1,11 @@
 package node
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"sync"
 
 	proto "github.com/fabrizioperria/blockchain/protobuf"
 	"github.com/fabrizioperria/blockchain/types"
@@ -12,10 +15,13 @@ type Headerer interface {
 	Length() int32
 	Height() int32
 	Add(*proto.Header) error
+	Pop() *proto.Header
 }
 
+// HeadersChain holds the headers of the main chain, indexed by height. It is safe for concurrent use.
 type HeadersChain struct {
 	Headerer
+	mu      sync.RWMutex
 	headers []*proto.Header
 }
 
@@ -24,23 +30,82 @@ func (hc *HeadersChain) Height() int32 {
 }
 
 func (hc *HeadersChain) Length() int32 {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+
 	return int32(len(hc.headers))
 }
 
+// Get returns the header at height, or nil when height is out of range.
+func (hc *HeadersChain) Get(height int32) *proto.Header {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+
+	if height < 0 || int(height) >= len(hc.headers) {
+		return nil
+	}
+	return hc.headers[height]
+}
+
 func (hc *HeadersChain) Add(header *proto.Header) error {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
 	hc.headers = append(hc.headers, header)
 	return nil
 }
 
+func (hc *HeadersChain) Pop() *proto.Header {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if len(hc.headers) == 0 {
+		return nil
+	}
+	header := hc.headers[len(hc.headers)-1]
+	hc.headers = hc.headers[:len(hc.headers)-1]
+	return header
+}
+
+// Indexer is kept in sync with the main chain: ConnectBlock and DisconnectBlock are called as blocks
+// join and leave it, reorgs included, along with the outputs the block spends.
+type Indexer interface {
+	ConnectBlock(block *proto.Block, height int32, spent []*UTXO) error
+	DisconnectBlock(block *proto.Block, height int32, spent []*UTXO) error
+	// Height is the height of the last block indexed, -1 when the index is empty.
+	Height() int32
+	Reset()
+}
+
+// ErrUnknownParent is returned by AddBlock for the blocks whose parent is not known yet.
+var ErrUnknownParent = errors.New("unknown parent block")
+
+// Chain is safe for concurrent use: blocks are added one at a time while readers see the chain as it was
+// before or after each of them.
 type Chain struct {
+	mu          sync.RWMutex
 	blockStorer BlockStorer
 	headers     *HeadersChain
+	utxos       *UTXOSet
+	// heights holds the height of every known block, on the main chain or on a side branch
+	heights   map[string]int32
+	undo      map[string][]*UTXO
+	indexers  []Indexer
+	txIndex   *TxIndex
+	addrIndex *AddrIndex
+	events    *EventBus
+	// verified holds the transactions whose signatures were already verified by the mempool
+	verified *sigCache
 }
 
 func NewChain(blockStorer BlockStorer) *Chain {
 	chain := &Chain{
 		blockStorer: blockStorer,
 		headers:     &HeadersChain{headers: []*proto.Header{}},
+		utxos:       NewUTXOSet(),
+		heights:     map[string]int32{},
+		undo:        map[string][]*UTXO{},
+		verified:    newSigCache(defaultMempoolSize),
 	}
 
 	genesisBlock := &proto.Block{
@@ -57,25 +122,273 @@ func NewChain(blockStorer BlockStorer) *Chain {
 	return chain
 }
 
+// AddBlock stores block and connects it when it extends the main chain. Malformed blocks are rejected, and
+// so are the blocks whose parent is unknown, with ErrUnknownParent, and the blocks spending outputs that are
+// not unspent on their branch once it is connected. A block extending a side branch
+// is only stored, unless that branch becomes longer than the main chain, in which case the chain reorganizes.
 func (c *Chain) AddBlock(block *proto.Block) error {
+	return c.addBlock(block, true)
+}
+
+// addBlock is AddBlock, leaving the signatures of block unchecked unless verifySignatures is set.
+func (c *Chain) addBlock(block *proto.Block, verifySignatures bool) error {
+	check := checkBlock
+	if verifySignatures {
+		check = func(block *proto.Block) error { return validateBlock(block, c.verified) }
+	}
+	if err := check(block); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash := hex.EncodeToString(types.HashBlockSHA256(block))
+	if _, ok := c.heights[hash]; ok {
+		return nil
+	}
+
+	parentHeight, parentKnown := c.heights[hex.EncodeToString(block.Header.PreviousHash)]
+	if !parentKnown && len(c.heights) > 0 {
+		return fmt.Errorf("%w %x", ErrUnknownParent, block.Header.PreviousHash)
+	}
+	if !parentKnown || parentHeight == c.height() && c.isMainChain(block.Header.PreviousHash, parentHeight) {
+		if err := c.blockStorer.Put(block); err != nil {
+			return err
+		}
+		if err := c.connectBlock(block); err != nil {
+			return err
+		}
+		c.heights[hash] = c.height()
+		c.events.Publish(Event{Kind: EventNewTip, Block: block, Height: c.height()})
+		return nil
+	}
+
+	if err := c.blockStorer.Put(block); err != nil {
+		return err
+	}
+	c.heights[hash] = parentHeight + 1
+	if parentHeight+1 <= c.height() {
+		return nil
+	}
+
+	disconnected, connected, err := c.reorganize(block)
+	if err != nil {
+		return err
+	}
+	c.events.Publish(Event{Kind: EventReorg, Disconnected: disconnected, Connected: connected, Height: c.height()})
+	c.events.Publish(Event{Kind: EventNewTip, Block: block, Height: c.height()})
+	return nil
+}
+
+func (c *Chain) isMainChain(hash []byte, height int32) bool {
+	header := c.headers.Get(height)
+	return header != nil && bytes.Equal(types.HashHeaderSHA256(header), hash)
+}
+
+// connectBlock makes block the tip of the main chain, unless its transactions do not spend outputs of the
+// main chain.
+func (c *Chain) connectBlock(block *proto.Block) error {
+	height := c.headers.Length()
+	spent, err := c.utxos.connectBlock(block, height)
+	if err != nil {
+		return err
+	}
 	c.headers.Add(block.Header)
-	return c.blockStorer.Put(block)
+	c.undo[hex.EncodeToString(types.HashBlockSHA256(block))] = spent
+	for _, indexer := range c.indexers {
+		if err := indexer.ConnectBlock(block, height, spent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Chain) disconnectTip() (*proto.Block, error) {
+	height := c.height()
+	block, err := c.blockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hex.EncodeToString(types.HashBlockSHA256(block))
+	for i := len(c.indexers) - 1; i >= 0; i-- {
+		if err := c.indexers[i].DisconnectBlock(block, height, c.undo[hash]); err != nil {
+			return nil, err
+		}
+	}
+	c.utxos.disconnectBlock(block, c.undo[hash])
+	delete(c.undo, hash)
+	c.headers.Pop()
+
+	return block, nil
+}
+
+// reorganize makes the branch ending with tip the main chain. It returns the blocks that left the main
+// chain, tip first, and the ones that joined it, oldest first. When a block of the branch cannot be
+// connected, the main chain is restored and that block and its descendants are forgotten.
+func (c *Chain) reorganize(tip *proto.Block) (disconnected []*proto.Block, connected []*proto.Block, err error) {
+	branch := []*proto.Block{tip}
+	for {
+		parentHash := branch[len(branch)-1].Header.PreviousHash
+		parentHeight, ok := c.heights[hex.EncodeToString(parentHash)]
+		if !ok {
+			return nil, nil, fmt.Errorf("block %x is not linked to the chain", parentHash)
+		}
+		if c.isMainChain(parentHash, parentHeight) {
+			break
+		}
+		parent, err := c.blockStorer.Get(hex.EncodeToString(parentHash))
+		if err != nil {
+			return nil, nil, err
+		}
+		branch = append(branch, parent)
+	}
+
+	forkHeight := c.heights[hex.EncodeToString(branch[len(branch)-1].Header.PreviousHash)]
+	for c.height() > forkHeight {
+		block, err := c.disconnectTip()
+		if err != nil {
+			return nil, nil, err
+		}
+		disconnected = append(disconnected, block)
+	}
+	for i := len(branch) - 1; i >= 0; i-- {
+		if err := c.connectBlock(branch[i]); err != nil {
+			for _, block := range branch[:i+1] {
+				delete(c.heights, hex.EncodeToString(types.HashBlockSHA256(block)))
+			}
+			return nil, nil, errors.Join(err, c.restore(len(connected), disconnected))
+		}
+		connected = append(connected, branch[i])
+	}
+
+	return disconnected, connected, nil
+}
+
+// restore undoes a failed reorganization: it disconnects the count blocks connected, then connects again the
+// blocks disconnected, tip first.
+func (c *Chain) restore(count int, disconnected []*proto.Block) error {
+	for i := 0; i < count; i++ {
+		if _, err := c.disconnectTip(); err != nil {
+			return err
+		}
+	}
+	for i := len(disconnected) - 1; i >= 0; i-- {
+		if err := c.connectBlock(disconnected[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddIndexer starts maintaining indexer along with the chain, rebuilding it first when it does not match
+// the current main chain.
+func (c *Chain) AddIndexer(indexer Indexer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.addIndexer(indexer)
+}
+
+func (c *Chain) addIndexer(indexer Indexer) error {
+	if indexer.Height() != c.height() {
+		indexer.Reset()
+		for height := int32(0); height <= c.height(); height++ {
+			block, err := c.blockByHeight(height)
+			if err != nil {
+				return err
+			}
+			spent := c.undo[hex.EncodeToString(types.HashBlockSHA256(block))]
+			if err := indexer.ConnectBlock(block, height, spent); err != nil {
+				return err
+			}
+		}
+	}
+	c.indexers = append(c.indexers, indexer)
+	return nil
+}
+
+func (c *Chain) EnableTxIndex() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.txIndex != nil {
+		return nil
+	}
+	index := NewTxIndex()
+	if err := c.addIndexer(index); err != nil {
+		return err
+	}
+	c.txIndex = index
+	return nil
+}
+
+func (c *Chain) EnableAddrIndex() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.addrIndex != nil {
+		return nil
+	}
+	index := NewAddrIndex()
+	if err := c.addIndexer(index); err != nil {
+		return err
+	}
+	c.addrIndex = index
+	return nil
 }
 
 func (c *Chain) GetBlockByHash(hash []byte) (*proto.Block, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	h := hex.EncodeToString(hash)
 	return c.blockStorer.Get(h)
 }
 
 func (c *Chain) GetBlockByHeight(height int32) (*proto.Block, error) {
-	if height < 0 || height >= c.headers.Length() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.blockByHeight(height)
+}
+
+func (c *Chain) blockByHeight(height int32) (*proto.Block, error) {
+	header := c.headers.Get(height)
+	if header == nil {
 		return nil, fmt.Errorf("block height %d out of range", height)
 	}
-	header := c.headers.headers[height]
 	hash := types.HashHeaderSHA256(header)
-	return c.GetBlockByHash(hash)
+	return c.blockStorer.Get(hex.EncodeToString(hash))
 }
 
 func (c *Chain) Height() int32 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.height()
+}
+
+func (c *Chain) height() int32 {
 	return c.headers.Height()
 }
+
+// heightOf returns the height of the block with hash, on the main chain or on a side branch.
+func (c *Chain) heightOf(hash []byte) (int32, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	height, ok := c.heights[hex.EncodeToString(hash)]
+	return height, ok
+}
+
+// Tip returns the last block of the main chain along with its height, both read at the same time.
+func (c *Chain) Tip() (*proto.Block, int32, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	height := c.height()
+	block, err := c.blockByHeight(height)
+	return block, height, err
+}
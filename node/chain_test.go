@@ -1,23 +1,80 @@
 package node
 
 import (
+	"sync"
 	"testing"
 
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
 	"github.com/fabrizioperria/blockchain/types"
 	"github.com/fabrizioperria/blockchain/utils"
 	"github.com/stretchr/testify/assert"
 )
 
+// addTestBlock extends the main chain of c with a block of transactions.
+func addTestBlock(t *testing.T, c *Chain, transactions ...*proto.Transaction) *proto.Block {
+	tip, err := c.GetBlockByHeight(c.Height())
+	assert.NoError(t, err)
+	block := utils.ChildBlock(t, tip, transactions...)
+	assert.NoError(t, c.AddBlock(block))
+	return block
+}
+
+// addTestBlocks extends the main chain of c with count empty blocks.
+func addTestBlocks(t *testing.T, c *Chain, count int) {
+	for i := 0; i < count; i++ {
+		addTestBlock(t, c)
+	}
+}
+
+// paymentChain returns a chain of count blocks past genesis, the first one minting coins that the second one
+// spends. When forged is set, the signature of the spend is invalid.
+func paymentChain(t *testing.T, count int, forged bool) *Chain {
+	c := NewChain(NewMemoryBlockStorer())
+	addPayments(t, c, count, forged)
+	return c
+}
+
+// addPayments adds the blocks of a paymentChain to c, which must be at its genesis block.
+func addPayments(t *testing.T, c *Chain, count int, forged bool) {
+	genesis, _ := c.GetBlockByHeight(0)
+	alice := crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	spend := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 10, DestAddress: alice.Public().Address().Bytes()})
+	if forged {
+		spend.Inputs[0].Signature[0] ^= 1
+	}
+
+	first := utils.ChildBlock(t, genesis, mint)
+	second := utils.ChildBlock(t, first, spend)
+	for _, block := range append([]*proto.Block{first, second}, utils.Branch(t, second, count-2)...) {
+		assert.NoError(t, c.addBlock(block, false))
+	}
+}
+
+// downloadChain returns the genesis block of n followed by count blocks extending it.
+func downloadChain(t *testing.T, n *Node, count int) []*proto.Block {
+	genesis, _ := n.chain.GetBlockByHeight(0)
+	return append([]*proto.Block{genesis}, utils.Branch(t, genesis, count)...)
+}
+
 func TestAddBlock(t *testing.T) {
 	bs := NewMemoryBlockStorer()
 	c := NewChain(bs)
-	block := utils.GenerateBlock(t, 1)
+	genesis, _ := c.GetBlockByHeight(0)
+	block := utils.ChildBlock(t, genesis)
 	assert.NoError(t, c.AddBlock(block))
 	hash := types.HashBlockSHA256(block)
 
 	fetchedBlock, err := c.GetBlockByHash(hash)
 	assert.NoError(t, err)
 	assert.Equal(t, block, fetchedBlock)
+
+	orphan := utils.GenerateBlock(t, 5)
+	assert.ErrorIs(t, c.AddBlock(orphan), ErrUnknownParent)
+	_, err = c.GetBlockByHash(types.HashBlockSHA256(orphan))
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), c.Height())
 }
 
 func TestChainHeight(t *testing.T) {
@@ -34,3 +91,146 @@ func TestChainHeight(t *testing.T) {
 		prev = types.HashBlockSHA256(block)
 	}
 }
+
+func TestChainReorganization(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	genesis, err := c.GetBlockByHeight(0)
+	assert.NoError(t, err)
+	alice := crypto.GeneratePrivateKey().Public().Address()
+	bob := crypto.GeneratePrivateKey().Public().Address()
+
+	main1 := utils.ChildBlock(t, genesis, utils.MintTransaction(alice, 10))
+	assert.NoError(t, c.AddBlock(main1))
+	side1 := utils.ChildBlock(t, genesis, utils.MintTransaction(bob, 20))
+	assert.NoError(t, c.AddBlock(side1))
+
+	// a side branch of the same length does not replace the main chain
+	tip, err := c.GetBlockByHeight(1)
+	assert.NoError(t, err)
+	assert.Equal(t, main1, tip)
+	assert.Equal(t, int64(10), c.utxos.Balance(alice.Bytes()))
+	assert.Zero(t, c.utxos.Balance(bob.Bytes()))
+
+	side2 := utils.ChildBlock(t, side1)
+	assert.NoError(t, c.AddBlock(side2))
+	assert.Equal(t, int32(2), c.Height())
+	tip, err = c.GetBlockByHeight(1)
+	assert.NoError(t, err)
+	assert.Equal(t, side1, tip)
+	assert.Zero(t, c.utxos.Balance(alice.Bytes()))
+	assert.Equal(t, int64(20), c.utxos.Balance(bob.Bytes()))
+
+	// the old branch can come back once it grows longer
+	main2 := utils.ChildBlock(t, main1)
+	main3 := utils.ChildBlock(t, main2)
+	assert.NoError(t, c.AddBlock(main2))
+	assert.NoError(t, c.AddBlock(main3))
+	assert.Equal(t, int32(3), c.Height())
+	assert.Equal(t, int64(10), c.utxos.Balance(alice.Bytes()))
+	assert.Zero(t, c.utxos.Balance(bob.Bytes()))
+}
+
+func TestChainRestoresSpentOutputsOnReorg(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	alice := crypto.GeneratePrivateKey()
+	bob := crypto.GeneratePrivateKey().Public().Address()
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	base := addTestBlock(t, c, mint)
+
+	spend := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 10, DestAddress: bob.Bytes()})
+	assert.NoError(t, c.AddBlock(utils.ChildBlock(t, base, spend)))
+	assert.Equal(t, int64(10), c.utxos.Balance(bob.Bytes()))
+
+	side1 := utils.ChildBlock(t, base)
+	assert.NoError(t, c.AddBlock(side1))
+	assert.NoError(t, c.AddBlock(utils.ChildBlock(t, side1)))
+	assert.Equal(t, int64(10), c.utxos.Balance(alice.Public().Address().Bytes()))
+	assert.Zero(t, c.utxos.Balance(bob.Bytes()))
+}
+
+func TestAddBlockIsIdempotent(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	block := addTestBlock(t, c)
+
+	assert.NoError(t, c.AddBlock(block))
+	assert.Equal(t, int32(1), c.Height())
+}
+
+// TestChainConcurrentAccess reads the chain while it keeps reorganizing between two branches: readers must
+// never see a chain in the middle of an update. Run with -race.
+func TestChainConcurrentAccess(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	genesis, _ := c.GetBlockByHeight(0)
+	branches := [2][]*proto.Block{{genesis}, {genesis}}
+	for i := range branches {
+		for len(branches[i]) <= 40 {
+			branches[i] = append(branches[i], utils.ChildBlock(t, branches[i][len(branches[i])-1]))
+		}
+	}
+	// each branch in turn grows one block longer than the other one, making the chain reorganize
+	order := []*proto.Block{}
+	lengths := [2]int{1, 1}
+	for turn := 0; lengths[turn%2] < len(branches[turn%2]); turn++ {
+		b, other := turn%2, (turn+1)%2
+		for lengths[b] <= lengths[other] && lengths[b] < len(branches[b]) {
+			order = append(order, branches[b][lengths[b]])
+			lengths[b]++
+		}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := int32(0)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				block, height, err := c.Tip()
+				assert.NoError(t, err)
+				assert.Equal(t, height, block.Header.Height)
+				assert.GreaterOrEqual(t, height, last)
+				last = height
+
+				block, err = c.GetBlockByHeight(height / 2)
+				assert.NoError(t, err)
+				assert.Equal(t, height/2, block.Header.Height)
+				c.Height()
+			}
+		}()
+	}
+
+	for _, block := range order {
+		assert.NoError(t, c.AddBlock(block))
+	}
+	close(done)
+	wg.Wait()
+	assert.Equal(t, int32(40), c.Height())
+}
+
+func TestHeadersChainConcurrentAccess(t *testing.T) {
+	hc := &HeadersChain{}
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				hc.Get(hc.Height())
+			}
+		}()
+	}
+	for i := 0; i < 1000; i++ {
+		hc.Add(&proto.Header{Height: int32(i)})
+		if i%3 == 0 {
+			hc.Pop()
+		}
+	}
+	wg.Wait()
+	assert.Equal(t, int32(666), hc.Length())
+}
@@ -0,0 +1,223 @@
+package node
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	chainFileVersion = 1
+	// maxChainFileRecord bounds the size of a block read from a chain file.
+	maxChainFileRecord = 4 << 20
+)
+
+var chainFileMagic = [4]byte{'B', 'C', 'H', 'F'}
+
+// ErrInvalidChainFile is returned when importing a file that is not a chain file of this network, or
+// whose blocks do not match its header.
+var ErrInvalidChainFile = errors.New("invalid chain file")
+
+// chainFileHeader starts a chain file, followed by the blocks from height From to height To, each of
+// them marshalled and prefixed by its length as a big endian uint32. Checksum is the SHA-256 of
+// everything following the header.
+type chainFileHeader struct {
+	Magic    [4]byte
+	Version  uint32
+	Network  [sha256.Size]byte
+	From     int32
+	To       int32
+	Checksum [sha256.Size]byte
+}
+
+func (h *chainFileHeader) info() *proto.ChainFileInfo {
+	return &proto.ChainFileInfo{
+		Network:    bytes.Clone(h.Network[:]),
+		FromHeight: h.From,
+		ToHeight:   h.To,
+		Checksum:   bytes.Clone(h.Checksum[:]),
+	}
+}
+
+// NetworkID identifies the network of the chain: it is the hash of its genesis block.
+func (c *Chain) NetworkID() []byte {
+	return types.HashHeaderSHA256(c.headers.Get(0))
+}
+
+// exportFile writes the blocks of the main chain from height from to height to into a chain file at
+// path. The chain cannot change while it is being exported.
+func (c *Chain) exportFile(path string, from int32, to int32) (*chainFileHeader, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if from < 0 || to < from || to > c.height() {
+		return nil, fmt.Errorf("invalid block range %d-%d at height %d", from, to, c.height())
+	}
+	header := &chainFileHeader{Magic: chainFileMagic, Version: chainFileVersion, From: from, To: to}
+	copy(header.Network[:], c.NetworkID())
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	// the header is written again once the checksum is known
+	if err := binary.Write(f, binary.BigEndian, header); err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewWriter(f)
+	checksum := sha256.New()
+	w := io.MultiWriter(buffered, checksum)
+	for height := from; height <= to; height++ {
+		block, err := c.blockByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := pb.Marshal(block)
+		if err != nil {
+			return nil, err
+		}
+		if err := binary.Write(w, binary.BigEndian, uint32(len(raw))); err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(raw); err != nil {
+			return nil, err
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		return nil, err
+	}
+
+	copy(header.Checksum[:], checksum.Sum(nil))
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(f, binary.BigEndian, header); err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return header, os.Rename(tmp, path)
+}
+
+// importFile adds the blocks of the chain file at path to the chain, once its header and checksum are
+// checked. The blocks are fully validated, except for the signatures of those below trustedHeight. The
+// blocks must follow each other, the first one extending the block of the chain below it. They are added
+// one at a time, so the ones before an invalid block stay in the chain: importFile returns the height of
+// the last block added, -1 when there is none.
+func (c *Chain) importFile(path string, trustedHeight int32) (*chainFileHeader, int32, error) {
+	imported := int32(-1)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, imported, err
+	}
+	defer f.Close()
+
+	header := &chainFileHeader{}
+	if err := binary.Read(f, binary.BigEndian, header); err != nil {
+		return nil, imported, fmt.Errorf("%w: reading header: %v", ErrInvalidChainFile, err)
+	}
+	if header.Magic != chainFileMagic {
+		return nil, imported, fmt.Errorf("%w: not a chain file", ErrInvalidChainFile)
+	}
+	if header.Version != chainFileVersion {
+		return nil, imported, fmt.Errorf("%w: unsupported version %d", ErrInvalidChainFile, header.Version)
+	}
+	if !bytes.Equal(header.Network[:], c.NetworkID()) {
+		return nil, imported, fmt.Errorf("%w: network %x instead of %x", ErrInvalidChainFile, header.Network, c.NetworkID())
+	}
+	if header.From < 0 || header.To < header.From {
+		return nil, imported, fmt.Errorf("%w: invalid block range %d-%d", ErrInvalidChainFile, header.From, header.To)
+	}
+
+	checksum := sha256.New()
+	if _, err := io.Copy(checksum, f); err != nil {
+		return nil, imported, err
+	}
+	if !bytes.Equal(checksum.Sum(nil), header.Checksum[:]) {
+		return nil, imported, fmt.Errorf("%w: checksum mismatch", ErrInvalidChainFile)
+	}
+	if _, err := f.Seek(int64(binary.Size(header)), io.SeekStart); err != nil {
+		return nil, imported, err
+	}
+
+	r := bufio.NewReader(f)
+	var prevHash []byte
+	for height := header.From; height <= header.To; height++ {
+		block, err := readChainFileBlock(r)
+		if err != nil {
+			return nil, imported, fmt.Errorf("%w: block %d: %v", ErrInvalidChainFile, height, err)
+		}
+		if err := c.checkFileBlock(block, height, prevHash); err != nil {
+			return nil, imported, fmt.Errorf("%w: block %d: %v", ErrInvalidChainFile, height, err)
+		}
+		if err := c.addBlock(block, height >= trustedHeight); err != nil {
+			return nil, imported, fmt.Errorf("block %d: %w", height, err)
+		}
+		imported = height
+		prevHash = types.HashBlockSHA256(block)
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return nil, imported, fmt.Errorf("%w: data after block %d", ErrInvalidChainFile, header.To)
+	}
+	return header, imported, nil
+}
+
+// checkFileBlock checks that block is at height: it extends the previous block of the file, whose hash is
+// prevHash, or the block of the chain at height-1 when it is the first one.
+func (c *Chain) checkFileBlock(block *proto.Block, height int32, prevHash []byte) error {
+	if block.GetHeader() == nil {
+		return errors.New("block has no header")
+	}
+	if block.Header.Height != height {
+		return fmt.Errorf("unexpected height %d", block.Header.Height)
+	}
+	switch {
+	case height == 0:
+		if !bytes.Equal(types.HashBlockSHA256(block), c.NetworkID()) {
+			return errors.New("unknown genesis block")
+		}
+	case prevHash != nil:
+		if !bytes.Equal(block.Header.PreviousHash, prevHash) {
+			return errors.New("does not extend the previous block")
+		}
+	default:
+		parent := c.headers.Get(height - 1)
+		if parent == nil || !bytes.Equal(block.Header.PreviousHash, types.HashHeaderSHA256(parent)) {
+			return fmt.Errorf("does not extend the chain at height %d", height-1)
+		}
+	}
+	return nil
+}
+
+func readChainFileBlock(r io.Reader) (*proto.Block, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size > maxChainFileRecord {
+		return nil, fmt.Errorf("block of %d bytes", size)
+	}
+	raw := make([]byte, size)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, err
+	}
+	block := &proto.Block{}
+	if err := pb.Unmarshal(raw, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
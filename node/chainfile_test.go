@@ -0,0 +1,159 @@
+package node
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestChainFileRoundTrip(t *testing.T) {
+	source := paymentChain(t, 6, false)
+	dir := t.TempDir()
+	head, tail := filepath.Join(dir, "head.chain"), filepath.Join(dir, "tail.chain")
+
+	header, err := source.exportFile(head, 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, source.NetworkID(), header.Network[:])
+	_, err = source.exportFile(tail, 3, source.Height())
+	assert.NoError(t, err)
+	_, err = source.exportFile(tail, 3, source.Height()+1)
+	assert.Error(t, err)
+
+	c := NewChain(NewMemoryBlockStorer())
+	_, _, err = c.importFile(tail, 0)
+	assert.ErrorIs(t, err, ErrInvalidChainFile)
+	imported, last, err := c.importFile(head, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, header, imported)
+	assert.Equal(t, int32(2), last)
+	_, _, err = c.importFile(tail, 0)
+	assert.NoError(t, err)
+	_, _, err = c.importFile(head, 0)
+	assert.NoError(t, err)
+
+	sourceTip, height, _ := source.Tip()
+	tip, _, _ := c.Tip()
+	assert.Equal(t, height, c.Height())
+	assert.Equal(t, types.HashBlockSHA256(sourceTip), types.HashBlockSHA256(tip))
+}
+
+func TestChainFileRejectsCorruption(t *testing.T) {
+	source := paymentChain(t, 4, false)
+	path := filepath.Join(t.TempDir(), "blocks.chain")
+	_, err := source.exportFile(path, 1, source.Height())
+	assert.NoError(t, err)
+	raw, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	corrupt := func(offset int) string {
+		corrupted := append([]byte{}, raw...)
+		corrupted[offset] ^= 1
+		assert.NoError(t, os.WriteFile(path, corrupted, 0o644))
+		return path
+	}
+	for name, offset := range map[string]int{"magic": 0, "version": 7, "network": 8, "checksum": 79, "block": len(raw) - 1} {
+		_, _, err := NewChain(NewMemoryBlockStorer()).importFile(corrupt(offset), 0)
+		assert.ErrorIs(t, err, ErrInvalidChainFile, name)
+	}
+
+	assert.NoError(t, os.WriteFile(path, raw[:len(raw)-1], 0o644))
+	_, _, err = NewChain(NewMemoryBlockStorer()).importFile(path, 0)
+	assert.ErrorIs(t, err, ErrInvalidChainFile)
+}
+
+func TestChainFileTrustedHeight(t *testing.T) {
+	source := paymentChain(t, 4, true)
+	path := filepath.Join(t.TempDir(), "forged.chain")
+	_, err := source.exportFile(path, 1, source.Height())
+	assert.NoError(t, err)
+
+	c := NewChain(NewMemoryBlockStorer())
+	// the forged block is at height 2: its signatures are verified unless it is below the trusted height
+	for _, trustedHeight := range []int32{1, 2} {
+		_, last, err := c.importFile(path, trustedHeight)
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, InvalidSignature, validationErr.Reason)
+		assert.Equal(t, int32(1), last)
+		assert.Equal(t, int32(1), c.Height())
+	}
+
+	_, _, err = c.importFile(path, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, source.Height(), c.Height())
+}
+
+func TestChainFileChecksTrustedBlocks(t *testing.T) {
+	alice := crypto.GeneratePrivateKey()
+	pay := &proto.TxOutput{Amount: 10, DestAddress: alice.Public().Address().Bytes()}
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	spend := utils.SpendTransaction(alice, mint, 0, pay)
+	// respend spends the output of mint again, once spend has spent it
+	respend := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 5, DestAddress: pay.DestAddress})
+
+	unrooted := func(parent *proto.Block) *proto.Block {
+		block := utils.ChildBlock(t, parent, utils.MintTransaction(alice.Public().Address(), 5))
+		block.Header.MerkleRoot = types.MerkleRoot(nil)
+		return block
+	}
+	for reason, invalidBlock := range map[string]func(parent *proto.Block) *proto.Block{
+		InvalidUnknownInput: func(parent *proto.Block) *proto.Block { return utils.ChildBlock(t, parent, respend) },
+		InvalidMerkleRoot:   unrooted,
+	} {
+		source := NewChain(NewMemoryBlockStorer())
+		genesis, _ := source.GetBlockByHeight(0)
+		first := utils.ChildBlock(t, genesis, mint)
+		second := utils.ChildBlock(t, first, spend)
+		assert.NoError(t, source.AddBlock(first))
+		assert.NoError(t, source.AddBlock(second))
+		// the chain file is written from a chain that skipped validating its tip
+		tip := invalidBlock(second)
+		assert.NoError(t, source.blockStorer.Put(tip))
+		source.headers.Add(tip.Header)
+		path := filepath.Join(t.TempDir(), "invalid.chain")
+		_, err := source.exportFile(path, 1, 3)
+		assert.NoError(t, err)
+
+		c := NewChain(NewMemoryBlockStorer())
+		_, last, err := c.importFile(path, 4)
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr, reason)
+		assert.Equal(t, reason, validationErr.Reason)
+		assert.Equal(t, int32(2), last, reason)
+		assert.Equal(t, int32(2), c.Height(), reason)
+	}
+}
+
+func TestAdminChainExportImport(t *testing.T) {
+	source := &adminServer{node: NewWithConfig(DefaultConfig())}
+	addPayments(t, source.node.chain, 3, false)
+	target := &adminServer{node: NewWithConfig(DefaultConfig())}
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "node.chain")
+
+	_, err := source.ExportChain(ctx, &proto.ChainExportRequest{Path: path, FromHeight: 2, ToHeight: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	info, err := source.ExportChain(ctx, &proto.ChainExportRequest{Path: path, FromHeight: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), info.ToHeight)
+
+	_, err = target.ImportChain(ctx, &proto.ChainImportRequest{Path: path + ".missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	info, err = target.ImportChain(ctx, &proto.ChainImportRequest{Path: path})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), info.Height)
+
+	assert.NoError(t, os.WriteFile(path, []byte("not a chain file"), 0o644))
+	_, err = target.ImportChain(ctx, &proto.ChainImportRequest{Path: path})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "no block imported")
+}
@@ -0,0 +1,145 @@
+package node
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and schedules the periodic work of a node. Nodes use the system clock unless
+// Config.Clock says otherwise.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	NewTicker(d time.Duration) Ticker
+	After(d time.Duration) <-chan time.Time
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// FakeClock only moves forward when Advance is called, firing the tickers and timers whose deadline it
+// crosses. Like with real tickers, ticks are dropped rather than queued when their receiver is behind.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters map[*fakeWaiter]bool
+}
+
+type fakeWaiter struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+	// period is zero for timers, which fire once.
+	period time.Duration
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, waiters: map[*fakeWaiter]bool{}}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return c.schedule(d, d)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.schedule(d, 0).c
+}
+
+func (c *FakeClock) schedule(d time.Duration, period time.Duration) *fakeWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &fakeWaiter{clock: c, c: make(chan time.Time, 1), deadline: c.now.Add(d), period: period}
+	if d <= 0 {
+		w.c <- c.now
+		return w
+	}
+	c.waiters[w] = true
+	return w
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for w := range c.waiters {
+		if w.deadline.After(c.now) {
+			continue
+		}
+		select {
+		case w.c <- c.now:
+		default:
+		}
+		if w.period == 0 {
+			delete(c.waiters, w)
+			continue
+		}
+		for !w.deadline.After(c.now) {
+			w.deadline = w.deadline.Add(w.period)
+		}
+	}
+}
+
+// Waiters returns the number of tickers and pending timers. Tests wait for it to know that the goroutines
+// they are about to trigger have scheduled their work.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.waiters)
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.c
+}
+
+func (w *fakeWaiter) Stop() {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	delete(w.clock.waiters, w)
+}
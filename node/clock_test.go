@@ -0,0 +1,43 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClockTicker(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := NewFakeClock(start)
+	ticker := clock.NewTicker(time.Second)
+
+	clock.Advance(999 * time.Millisecond)
+	assert.Len(t, ticker.C(), 0)
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, start.Add(time.Second), <-ticker.C())
+
+	clock.Advance(3 * time.Second)
+	assert.Equal(t, start.Add(4*time.Second), <-ticker.C())
+	assert.Len(t, ticker.C(), 0)
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(5*time.Second), <-ticker.C())
+
+	ticker.Stop()
+	assert.Equal(t, 0, clock.Waiters())
+	clock.Advance(time.Second)
+	assert.Len(t, ticker.C(), 0)
+}
+
+func TestFakeClockAfter(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	assert.Len(t, clock.After(0), 1)
+
+	c := clock.After(time.Minute)
+	assert.Equal(t, 1, clock.Waiters())
+	clock.Advance(time.Second)
+	assert.Len(t, c, 0)
+	clock.Advance(time.Minute)
+	assert.Equal(t, clock.Now(), <-c)
+	assert.Equal(t, 0, clock.Waiters())
+}
@@ -0,0 +1,55 @@
+package node
+
+import (
+	"path"
+	"strings"
+
+	"github.com/fabrizioperria/blockchain/logging"
+)
+
+type Config struct {
+	// Transport carries the connections between peers, TCP when nil.
+	Transport Transport
+	// Clock tells the time to the node, the system clock when nil.
+	Clock Clock
+	// Log configures the format, levels and output of the logs. The file defaults to logs/<listen address>.log.
+	Log logging.Config
+	// DataDir is where the node persists its state. When empty it defaults to data/<listen address>.
+	DataDir  string
+	MaxPeers int
+	TLS      *TLSConfig
+	// Services are advertised to peers, which are rejected unless they offer all of RequiredServices.
+	Services         ServiceFlag
+	RequiredServices ServiceFlag
+	// TxIndex maintains an index of the transactions of the main chain by hash.
+	TxIndex bool
+	// AddressIndex maintains the history of credits and debits of every address.
+	AddressIndex bool
+	// AdminListenAddr serves the admin API on its own plaintext listener instead of the peer one.
+	AdminListenAddr string
+	// MempoolSize is the number of transactions the mempool holds before evicting the oldest ones.
+	MempoolSize int
+	// HTTPListenAddr, when set, serves the Query API as JSON over HTTP on that address.
+	HTTPListenAddr string
+	// Explorer serves a read-only block explorer under /explorer/ on HTTPListenAddr.
+	Explorer bool
+	// MetricsListenAddr, when set, serves metrics in the Prometheus text format at /metrics on that address.
+	MetricsListenAddr string
+	// AdminToken lets non-local clients use the admin API when sent as "authorization: Bearer <token>".
+	AdminToken string
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		MaxPeers:    32,
+		Services:    ServiceFullNode,
+		MempoolSize: defaultMempoolSize,
+	}
+}
+
+func (c *Config) dataDir(listenAddr string) string {
+	if c.DataDir != "" {
+		return c.DataDir
+	}
+	return path.Join("data", strings.ReplaceAll(listenAddr, ":", ""))
+}
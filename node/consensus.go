@@ -0,0 +1,74 @@
+package node
+
+import (
+	"bytes"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+)
+
+// checkMerkleRoot checks that the header of block commits to its transactions. This is a consensus rule of
+// protocol version 2.0.0: the hash of a block only covers its header, so without it the transactions of a
+// block could be replaced without invalidating its hash, and version 1 nodes accept any Merkle root.
+func checkMerkleRoot(block *proto.Block) error {
+	if !bytes.Equal(block.Header.MerkleRoot, types.MerkleRoot(block.Transaction)) {
+		return invalid(InvalidMerkleRoot, "merkle root does not match the transactions")
+	}
+	return nil
+}
+
+// blockSpends are the outputs created and spent by the transactions of a block, the spent ones in the order
+// they were spent.
+type blockSpends struct {
+	created map[string]*UTXO
+	spent   map[string]bool
+	order   []string
+}
+
+// spendBlock checks that the transactions of block, at height, only spend outputs found by utxo or created
+// by the transactions before them in block, each output once, as spendInputs requires. Transactions without
+// inputs mint their outputs. This is a consensus rule of protocol version 2.0.0: version 1 nodes do not check
+// the spends of blocks, so their blocks may spend unknown outputs and create coins.
+func spendBlock(block *proto.Block, height int32, utxo func(key string) (*UTXO, bool)) (*blockSpends, error) {
+	spends := &blockSpends{created: map[string]*UTXO{}, spent: map[string]bool{}}
+	lookup := func(key string) (*UTXO, bool) {
+		if spends.spent[key] {
+			return nil, false
+		}
+		if created, ok := spends.created[key]; ok {
+			return created, true
+		}
+		return utxo(key)
+	}
+
+	for i, tx := range block.Transaction {
+		if len(tx.Inputs) > 0 {
+			for j, input := range tx.Inputs {
+				if key := outpointKey(input.PreviousTxHash, input.PrevOutputIndex); spends.spent[key] {
+					return nil, invalid(InvalidDoubleSpend, "transaction %d: input %d spends %s spent earlier in the block", i, j, key)
+				}
+			}
+			utxos, err := spendInputs(tx, lookup)
+			if err != nil {
+				e := err.(*ValidationError)
+				return nil, invalid(e.Reason, "transaction %d: %w", i, e.err)
+			}
+			for _, utxo := range utxos {
+				key := outpointKey(utxo.TxHash, utxo.OutputIndex)
+				spends.spent[key] = true
+				spends.order = append(spends.order, key)
+			}
+		}
+
+		hash := types.HashTransactionSHA256(tx)
+		for j, output := range tx.Outputs {
+			spends.created[outpointKey(hash, int32(j))] = &UTXO{
+				TxHash:      hash,
+				OutputIndex: int32(j),
+				Output:      output,
+				Height:      height,
+			}
+		}
+	}
+	return spends, nil
+}
@@ -0,0 +1,103 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckMerkleRoot(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	genesis, _ := c.GetBlockByHeight(0)
+	alice := crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	other := utils.MintTransaction(alice.Public().Address(), 5)
+
+	block := utils.ChildBlock(t, genesis, mint, other)
+	assert.NoError(t, checkMerkleRoot(block))
+	hash := types.HashBlockSHA256(block)
+
+	// the transactions of a block change without changing its hash
+	for name, transactions := range map[string][]*proto.Transaction{
+		"added":     {mint, other, utils.MintTransaction(alice.Public().Address(), 1)},
+		"removed":   {mint},
+		"reordered": {other, mint},
+		"replaced":  {mint, utils.MintTransaction(alice.Public().Address(), 6)},
+	} {
+		block.Transaction = transactions
+		assert.Equal(t, hash, types.HashBlockSHA256(block), name)
+		var validationErr *ValidationError
+		assert.ErrorAs(t, validateBlock(block, c.verified), &validationErr, name)
+		assert.Equal(t, InvalidMerkleRoot, validationErr.Reason, name)
+		assert.Error(t, c.AddBlock(block), name)
+	}
+	assert.Zero(t, c.Height())
+}
+
+func TestChainValidatesSpends(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	genesis, _ := c.GetBlockByHeight(0)
+	alice, bob := crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	base := utils.ChildBlock(t, genesis, mint)
+	assert.NoError(t, c.AddBlock(base))
+	pay := func(key *crypto.PrivateKey, amount int64) *proto.TxOutput {
+		return &proto.TxOutput{Amount: amount, DestAddress: key.Public().Address().Bytes()}
+	}
+
+	first := utils.SpendTransaction(alice, mint, 0, pay(bob, 10))
+	for reason, transactions := range map[string][]*proto.Transaction{
+		InvalidUnknownInput: {utils.SpendTransaction(alice, utils.MintTransaction(alice.Public().Address(), 20), 0, pay(bob, 10))},
+		InvalidDoubleSpend:  {first, utils.SpendTransaction(alice, mint, 0, pay(alice, 10))},
+		InvalidOwner:        {utils.SpendTransaction(bob, mint, 0, pay(bob, 10))},
+		InvalidOverspend:    {utils.SpendTransaction(alice, mint, 0, pay(bob, 11))},
+	} {
+		var validationErr *ValidationError
+		assert.ErrorAs(t, c.AddBlock(utils.ChildBlock(t, base, transactions...)), &validationErr, reason)
+		assert.Equal(t, reason, validationErr.Reason)
+		assert.Equal(t, int32(1), c.Height(), reason)
+		assert.Equal(t, int64(10), c.utxos.Balance(alice.Public().Address().Bytes()), reason)
+	}
+
+	// an output can be spent by a later transaction of the block creating it
+	block := utils.ChildBlock(t, base, first, utils.SpendTransaction(bob, first, 0, pay(alice, 10)))
+	assert.NoError(t, c.AddBlock(block))
+	assert.Equal(t, int64(10), c.utxos.Balance(alice.Public().Address().Bytes()))
+	assert.Zero(t, c.utxos.Balance(bob.Public().Address().Bytes()))
+
+	// disconnecting it only brings back the output it spent from the chain
+	branch := utils.Branch(t, base, 2)
+	for _, block := range branch {
+		assert.NoError(t, c.AddBlock(block))
+	}
+	assert.Equal(t, types.HashBlockSHA256(branch[1]), types.HashHeaderSHA256(c.headers.Get(c.Height())))
+	assert.Len(t, c.utxos.ByAddress(alice.Public().Address().Bytes()), 1)
+	assert.Zero(t, c.utxos.Balance(bob.Public().Address().Bytes()))
+}
+
+func TestChainRejectsReorganizationToInvalidBranch(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	genesis, _ := c.GetBlockByHeight(0)
+	alice := crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	main := utils.ChildBlock(t, genesis, mint)
+	assert.NoError(t, c.AddBlock(main))
+
+	// the side branch spends an output that only exists on the main chain
+	spend := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 10, DestAddress: alice.Public().Address().Bytes()})
+	side := utils.ChildBlock(t, genesis)
+	invalidBlock := utils.ChildBlock(t, side, spend)
+	assert.NoError(t, c.AddBlock(side))
+	var validationErr *ValidationError
+	assert.ErrorAs(t, c.AddBlock(invalidBlock), &validationErr)
+	assert.Equal(t, InvalidUnknownInput, validationErr.Reason)
+
+	assert.Equal(t, int32(1), c.Height())
+	assert.Equal(t, types.HashBlockSHA256(main), types.HashHeaderSHA256(c.headers.Get(1)))
+	assert.Equal(t, int64(10), c.utxos.Balance(alice.Public().Address().Bytes()))
+	assert.ErrorIs(t, c.AddBlock(utils.ChildBlock(t, invalidBlock)), ErrUnknownParent)
+}
@@ -0,0 +1,242 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// downloadChunkSize is the number of blocks asked to a peer at once, and downloadWindow the number of
+	// chunks that can be downloaded ahead of the next block to connect.
+	downloadChunkSize = 16
+	downloadWindow    = 8
+	// a request still running after downloadStallTimeout is given to another peer.
+	downloadStallTimeout  = 15 * time.Second
+	downloadStallInterval = time.Second
+	// maxDownloadStalls is the number of stalled requests after which a peer is disconnected.
+	maxDownloadStalls = 3
+)
+
+type blockRange struct {
+	from, to int32
+}
+
+type rangeRequest struct {
+	blockRange
+	peer    *addPeerData
+	started time.Time
+	cancel  context.CancelFunc
+}
+
+type rangeResult struct {
+	request *rangeRequest
+	blocks  []*proto.Block
+	err     error
+}
+
+// blockDownload fetches a range of blocks from several peers at once, each serving a chunk of it at a time,
+// and connects them in order. The chunks stalled or failed by a peer are given to the other ones, and the
+// peers stalling too often are disconnected.
+type blockDownload struct {
+	node  *Node
+	peers []*addPeerData
+	// fetch returns the blocks of r from peer, in order.
+	fetch func(ctx context.Context, peer *addPeerData, r blockRange) ([]*proto.Block, error)
+
+	next     int32
+	to       int32
+	retries  []blockRange
+	inflight map[*addPeerData]*rangeRequest
+	excluded map[*addPeerData]bool
+	done     map[int32]*rangeResult
+	results  chan *rangeResult
+}
+
+func (n *Node) newBlockDownload(peers []*addPeerData, from int32, to int32) *blockDownload {
+	return &blockDownload{
+		node:     n,
+		peers:    peers,
+		fetch:    n.fetchRange,
+		next:     from,
+		to:       to,
+		inflight: map[*addPeerData]*rangeRequest{},
+		excluded: map[*addPeerData]bool{},
+		done:     map[int32]*rangeResult{},
+		results:  make(chan *rangeResult, len(peers)),
+	}
+}
+
+// run downloads and connects the blocks up to the end of the range. It stops when no peer is left to
+// serve the missing blocks.
+func (d *blockDownload) run() error {
+	ticker := d.node.clock.NewTicker(downloadStallInterval)
+	defer ticker.Stop()
+	defer func() {
+		for _, request := range d.inflight {
+			request.cancel()
+		}
+	}()
+
+	connectNext := d.next
+	for connectNext <= d.to {
+		d.assign(connectNext)
+		if len(d.inflight) == 0 {
+			return fmt.Errorf("no peer can serve blocks %d to %d", connectNext, d.to)
+		}
+
+		select {
+		case result := <-d.results:
+			if d.inflight[result.request.peer] != result.request {
+				continue
+			}
+			delete(d.inflight, result.request.peer)
+			if result.err != nil {
+				d.fail(result.request, result.err)
+				d.retries = append(d.retries, result.request.blockRange)
+				continue
+			}
+			d.done[result.request.from] = result
+
+			for d.done[connectNext] != nil {
+				result := d.done[connectNext]
+				delete(d.done, connectNext)
+				connected, err := d.connect(result)
+				connectNext += connected
+				if err != nil {
+					d.fail(result.request, err)
+					if connectNext <= result.request.to {
+						d.retries = append(d.retries, blockRange{from: connectNext, to: result.request.to})
+					}
+					break
+				}
+			}
+		case <-ticker.C():
+			d.checkStalls()
+		}
+	}
+	return nil
+}
+
+// assign gives a chunk to every idle peer, as long as the chunks stay within the window starting at
+// connectNext.
+func (d *blockDownload) assign(connectNext int32) {
+	for _, peer := range d.peers {
+		if d.excluded[peer] || d.inflight[peer] != nil {
+			continue
+		}
+		r, ok := d.nextRange(connectNext, peer)
+		if !ok {
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		request := &rangeRequest{blockRange: r, peer: peer, started: d.node.clock.Now(), cancel: cancel}
+		d.inflight[peer] = request
+		go func() {
+			blocks, err := d.fetch(ctx, peer, r)
+			d.results <- &rangeResult{request: request, blocks: blocks, err: err}
+		}()
+	}
+}
+
+// nextRange returns the first chunk to retry that peer can serve, or else the next chunk of the window.
+func (d *blockDownload) nextRange(connectNext int32, peer *addPeerData) (blockRange, bool) {
+	for i, r := range d.retries {
+		if peer.data.Height >= r.to {
+			d.retries = append(d.retries[:i], d.retries[i+1:]...)
+			return r, true
+		}
+	}
+
+	if d.next > d.to || d.next >= connectNext+downloadWindow*downloadChunkSize {
+		return blockRange{}, false
+	}
+	r := blockRange{from: d.next, to: min(d.next+downloadChunkSize-1, d.to)}
+	if peer.data.Height < r.to {
+		return blockRange{}, false
+	}
+	d.next = r.to + 1
+	return r, true
+}
+
+// connect adds the blocks of result to the chain and returns how many of them joined it.
+func (d *blockDownload) connect(result *rangeResult) (int32, error) {
+	for i, block := range result.blocks {
+		if err := d.node.processBlock(result.request.peer, block); err != nil {
+			return int32(i), err
+		}
+	}
+	return int32(len(result.blocks)), nil
+}
+
+// fail leaves the rest of the download to the peers other than the one serving request.
+func (d *blockDownload) fail(request *rangeRequest, err error) {
+	d.node.chainLogger.WithFields(logrus.Fields{
+		"peer": request.peer.data.Address,
+		"from": request.from,
+		"to":   request.to,
+	}).Warnf("Block download failed: %v", err)
+	d.excluded[request.peer] = true
+}
+
+// checkStalls takes the requests running for too long away from their peer, which is disconnected once it
+// stalled too often.
+func (d *blockDownload) checkStalls() {
+	now := d.node.clock.Now()
+	for peer, request := range d.inflight {
+		if now.Sub(request.started) < downloadStallTimeout {
+			continue
+		}
+		request.cancel()
+		delete(d.inflight, peer)
+		d.excluded[peer] = true
+		d.retries = append(d.retries, request.blockRange)
+
+		stalls := peer.stalls.Add(1)
+		logger := d.node.chainLogger.WithFields(logrus.Fields{
+			"peer":   peer.data.Address,
+			"from":   request.from,
+			"to":     request.to,
+			"stalls": stalls,
+		})
+		logger.Warn("Block download stalled")
+		if stalls >= maxDownloadStalls {
+			logger.Warn("Disconnecting slow peer")
+			d.node.removePeer(peer.id())
+		}
+	}
+}
+
+// fetchRange downloads r from peer, checking that it gets all the blocks it asked for.
+func (n *Node) fetchRange(ctx context.Context, peer *addPeerData, r blockRange) ([]*proto.Block, error) {
+	stream, err := (*peer.client).GetBlocks(ctx, &proto.BlockRange{FromHeight: r.from, ToHeight: r.to})
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := []*proto.Block{}
+	for {
+		block, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) != int(r.to-r.from+1) {
+		return nil, fmt.Errorf("peer sent %d blocks out of %d", len(blocks), r.to-r.from+1)
+	}
+	for i, block := range blocks {
+		if block.Header.GetHeight() != r.from+int32(i) {
+			return nil, fmt.Errorf("peer sent block %d instead of %d", block.Header.GetHeight(), r.from+int32(i))
+		}
+	}
+	return blocks, nil
+}
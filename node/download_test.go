@@ -0,0 +1,141 @@
+package node
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// downloadPeer returns a peer announcing height, whose connection is never used.
+func downloadPeer(t *testing.T, address string, height int32) *addPeerData {
+	publicKey := make([]byte, 32)
+	rand.Read(publicKey)
+	conn, err := grpc.NewClient("passthrough:///"+address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	return &addPeerData{
+		conn:  conn,
+		stats: &connStats{},
+		data:  &proto.HandshakeMsg{Address: address, Height: height, PublicKey: publicKey},
+	}
+}
+
+// fakeFetch serves the blocks of chain, each of them at its height, from the peers that behave. It counts
+// the requests made to every peer.
+type fakeFetch struct {
+	mu       sync.Mutex
+	chain    []*proto.Block
+	requests map[string]int
+	// stalling peers never answer and failing ones return an error.
+	stalling map[string]chan struct{}
+	failing  map[string]bool
+}
+
+func newFakeFetch(chain []*proto.Block) *fakeFetch {
+	return &fakeFetch{chain: chain, requests: map[string]int{}, stalling: map[string]chan struct{}{}, failing: map[string]bool{}}
+}
+
+func (f *fakeFetch) fetch(ctx context.Context, peer *addPeerData, r blockRange) ([]*proto.Block, error) {
+	f.mu.Lock()
+	f.requests[peer.data.Address]++
+	stalled, stalling := f.stalling[peer.data.Address]
+	failing := f.failing[peer.data.Address]
+	f.mu.Unlock()
+
+	if stalling {
+		close(stalled)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if failing {
+		return nil, errors.New("connection reset")
+	}
+	return f.chain[r.from : r.to+1], nil
+}
+
+func (f *fakeFetch) count(address string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.requests[address]
+}
+
+func TestBlockDownloadSpreadsRangesOverPeers(t *testing.T) {
+	n := NewWithConfig(DefaultConfig())
+	chain := downloadChain(t, n, 10*downloadChunkSize)
+	peers := []*addPeerData{downloadPeer(t, "a", 1000), downloadPeer(t, "b", 1000), downloadPeer(t, "c", 1000)}
+	f := newFakeFetch(chain)
+
+	d := n.newBlockDownload(peers, 1, int32(len(chain)-1))
+	d.fetch = f.fetch
+	assert.NoError(t, d.run())
+
+	assert.Equal(t, int32(len(chain)-1), n.chain.Height())
+	for _, peer := range peers {
+		assert.NotZero(t, f.count(peer.data.Address), peer.data.Address)
+	}
+}
+
+func TestBlockDownloadOnlyAsksPeersHoldingTheBlocks(t *testing.T) {
+	n := NewWithConfig(DefaultConfig())
+	chain := downloadChain(t, n, 4*downloadChunkSize)
+	short := downloadPeer(t, "short", downloadChunkSize)
+	f := newFakeFetch(chain)
+
+	d := n.newBlockDownload([]*addPeerData{short, downloadPeer(t, "long", 1000)}, 1, int32(len(chain)-1))
+	d.fetch = f.fetch
+	assert.NoError(t, d.run())
+	assert.Equal(t, int32(len(chain)-1), n.chain.Height())
+	assert.LessOrEqual(t, f.count("short"), 1)
+
+	d = n.newBlockDownload([]*addPeerData{short}, n.chain.Height()+1, n.chain.Height()+1)
+	assert.Error(t, d.run())
+}
+
+func TestBlockDownloadRetriesFailedRanges(t *testing.T) {
+	n := NewWithConfig(DefaultConfig())
+	chain := downloadChain(t, n, 4*downloadChunkSize)
+	f := newFakeFetch(chain)
+	f.failing["bad"] = true
+
+	d := n.newBlockDownload([]*addPeerData{downloadPeer(t, "bad", 1000), downloadPeer(t, "good", 1000)}, 1, int32(len(chain)-1))
+	d.fetch = f.fetch
+	assert.NoError(t, d.run())
+	assert.Equal(t, int32(len(chain)-1), n.chain.Height())
+	assert.Equal(t, 1, f.count("bad"))
+}
+
+func TestBlockDownloadReassignsStalledRanges(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	config := DefaultConfig()
+	config.Clock = clock
+	n := NewWithConfig(config)
+	// a single chunk, given to slow, leaves fast idle until the chunk stalls
+	chain := downloadChain(t, n, downloadChunkSize)
+	slow := downloadPeer(t, "slow", 1000)
+	slow.stalls.Store(maxDownloadStalls - 1)
+	n.addPeerCh <- slow
+	assert.Eventually(t, func() bool { return n.findPeer("slow") != nil }, time.Second, time.Millisecond)
+
+	f := newFakeFetch(chain)
+	stalled := make(chan struct{})
+	f.stalling["slow"] = stalled
+	d := n.newBlockDownload([]*addPeerData{slow, downloadPeer(t, "fast", 1000)}, 1, int32(len(chain)-1))
+	d.fetch = f.fetch
+	result := make(chan error)
+	go func() { result <- d.run() }()
+
+	<-stalled
+	clock.Advance(downloadStallTimeout)
+	assert.NoError(t, <-result)
+	assert.Equal(t, int32(len(chain)-1), n.chain.Height())
+	assert.Equal(t, int32(maxDownloadStalls), slow.stalls.Load())
+	assert.Eventually(t, func() bool { return n.findPeer("slow") == nil }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,125 @@
+package node
+
+import (
+	"sync"
+	"sync/atomic"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+)
+
+type EventKind int
+
+const (
+	EventNewTip EventKind = iota
+	EventReorg
+	EventMempoolAccept
+	EventMempoolEvict
+	EventPeerConnected
+	EventPeerDisconnected
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventNewTip:
+		return "new-tip"
+	case EventReorg:
+		return "reorg"
+	case EventMempoolAccept:
+		return "mempool-accept"
+	case EventMempoolEvict:
+		return "mempool-evict"
+	case EventPeerConnected:
+		return "peer-connected"
+	case EventPeerDisconnected:
+		return "peer-disconnected"
+	default:
+		return "unknown"
+	}
+}
+
+// Event carries the fields relevant to its kind: Block and Height for a new tip, Disconnected and Connected
+// for a reorg, Transaction and Reason for the mempool, Peer for peer changes.
+type Event struct {
+	Kind         EventKind
+	Block        *proto.Block
+	Height       int32
+	Disconnected []*proto.Block
+	Connected    []*proto.Block
+	Transaction  *proto.Transaction
+	Reason       string
+	Peer         *proto.PeerInfo
+}
+
+// EventBus lets the chain, the mempool and the peer manager publish what happens to them without
+// knowing who listens.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: map[*Subscription]struct{}{}}
+}
+
+// Subscribe returns a subscription receiving the events of the given kinds, or all of them when none is given.
+// Events are dropped rather than blocking the publisher when the subscriber falls more than bufferSize behind.
+func (b *EventBus) Subscribe(bufferSize int, kinds ...EventKind) *Subscription {
+	ch := make(chan Event, bufferSize)
+	s := &Subscription{C: ch, ch: ch, bus: b, kinds: map[EventKind]bool{}}
+	for _, kind := range kinds {
+		s.kinds[kind] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[s] = struct{}{}
+
+	return s
+}
+
+func (b *EventBus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subs {
+		if len(s.kinds) > 0 && !s.kinds[event.Kind] {
+			continue
+		}
+		select {
+		case s.ch <- event:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+func (b *EventBus) unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+type Subscription struct {
+	C       <-chan Event
+	ch      chan Event
+	bus     *EventBus
+	kinds   map[EventKind]bool
+	dropped atomic.Uint64
+}
+
+// Dropped is the number of events lost because the subscriber was too slow.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
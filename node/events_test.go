@@ -0,0 +1,76 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBusFiltersByKind(t *testing.T) {
+	bus := NewEventBus()
+	tips := bus.Subscribe(10, EventNewTip)
+	all := bus.Subscribe(10)
+
+	bus.Publish(Event{Kind: EventNewTip, Height: 1})
+	bus.Publish(Event{Kind: EventMempoolAccept})
+
+	assert.Len(t, tips.C, 1)
+	assert.Equal(t, EventNewTip, (<-tips.C).Kind)
+	assert.Len(t, all.C, 2)
+}
+
+func TestEventBusDropsForSlowSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(1)
+
+	bus.Publish(Event{Kind: EventNewTip, Height: 1})
+	bus.Publish(Event{Kind: EventNewTip, Height: 2})
+
+	assert.Equal(t, uint64(1), sub.Dropped())
+	assert.Equal(t, int32(1), (<-sub.C).Height)
+}
+
+func TestEventBusClose(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(1)
+	sub.Close()
+	sub.Close()
+
+	bus.Publish(Event{Kind: EventNewTip})
+	_, ok := <-sub.C
+	assert.False(t, ok)
+}
+
+func TestChainPublishesTipsAndReorgs(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	c.events = NewEventBus()
+	sub := c.events.Subscribe(10)
+	genesis, _ := c.GetBlockByHeight(0)
+
+	a1 := utils.ChildBlock(t, genesis, utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 1))
+	assert.NoError(t, c.AddBlock(a1))
+	event := <-sub.C
+	assert.Equal(t, EventNewTip, event.Kind)
+	assert.Equal(t, a1, event.Block)
+	assert.Equal(t, int32(1), event.Height)
+
+	b1 := utils.ChildBlock(t, genesis, utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 2))
+	assert.NoError(t, c.AddBlock(b1))
+	assert.Len(t, sub.C, 0)
+
+	b2 := utils.ChildBlock(t, b1)
+	assert.NoError(t, c.AddBlock(b2))
+	event = <-sub.C
+	assert.Equal(t, EventReorg, event.Kind)
+	assert.Equal(t, a1, event.Disconnected[0])
+	assert.Len(t, event.Disconnected, 1)
+	assert.Equal(t, b1, event.Connected[0])
+	assert.Equal(t, b2, event.Connected[1])
+	assert.Equal(t, int32(2), event.Height)
+
+	event = <-sub.C
+	assert.Equal(t, EventNewTip, event.Kind)
+	assert.Equal(t, b2, event.Block)
+}
@@ -0,0 +1,68 @@
+package node
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/fabrizioperria/blockchain/explorer"
+	"github.com/fabrizioperria/blockchain/gateway"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const gatewayBufferSize = 1 << 20
+
+// serveGateway serves the Query API as JSON on config.HTTPListenAddr, along with the explorer when enabled.
+// Both reach the node services through an in-memory connection rather than through the peer listener and its
+// transport security.
+func (n *Node) serveGateway() {
+	listener := bufconn.Listen(gatewayBufferSize)
+	go n.gatewayServer().Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///gateway",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		n.rpcLogger.Fatalf("failed to connect the HTTP gateway: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", gateway.New(proto.NewQueryClient(conn)))
+	if n.config.Explorer {
+		mux.Handle("/explorer/", http.StripPrefix("/explorer", explorer.New(proto.NewQueryClient(conn), proto.NewAdminClient(conn))))
+		n.rpcLogger.Infof("Explorer started on %s/explorer/", n.config.HTTPListenAddr)
+	}
+
+	n.rpcLogger.Infof("HTTP gateway started on %s", n.config.HTTPListenAddr)
+	if err := http.ListenAndServe(n.config.HTTPListenAddr, mux); err != nil {
+		n.rpcLogger.Fatalf("failed to serve the HTTP gateway: %v", err)
+	}
+}
+
+// gatewayServer serves the services reached through the HTTP gateway. Its calls do not come from localhost
+// and carry no admin token, so of the Admin service it only serves the peer list read by the explorer.
+func (n *Node) gatewayServer() *grpc.Server {
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(n.metrics.unaryInterceptor),
+		grpc.ChainStreamInterceptor(n.metrics.streamInterceptor),
+	)
+	proto.RegisterQueryServer(grpcServer, n.queryServer())
+	proto.RegisterAdminServer(grpcServer, &peerInfoServer{admin: &adminServer{node: n}})
+	return grpcServer
+}
+
+// peerInfoServer is the read-only part of the Admin service: every other call is unimplemented.
+type peerInfoServer struct {
+	proto.UnimplementedAdminServer
+	admin *adminServer
+}
+
+func (s *peerInfoServer) GetPeerInfo(ctx context.Context, req *proto.Ack) (*proto.PeerInfoList, error) {
+	return s.admin.GetPeerInfo(ctx, req)
+}
@@ -0,0 +1,43 @@
+package node
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGatewayOnlyReadsPeers(t *testing.T) {
+	n := NewWithConfig(DefaultConfig())
+	listener := bufconn.Listen(gatewayBufferSize)
+	server := n.gatewayServer()
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///gateway",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+	admin := proto.NewAdminClient(conn)
+	ctx := context.Background()
+
+	_, err = admin.GetPeerInfo(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+	_, err = admin.BanPeer(ctx, &proto.BanRequest{Target: "10.0.0.1:3000"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = admin.ImportChain(ctx, &proto.ChainImportRequest{Path: "/etc/passwd"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = proto.NewQueryClient(conn).GetTip(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+}
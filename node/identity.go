@@ -0,0 +1,169 @@
+package node
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	identityKeyFile = "identity.key"
+	nonceSize       = 32
+	challengeExpiry = 30 * time.Second
+	// maxChallenges bounds the challenges outstanding, and maxChallengesPerRemote those of a single host.
+	maxChallenges          = 1024
+	maxChallengesPerRemote = 8
+)
+
+func loadOrCreateIdentity(dataDir string) (*crypto.PrivateKey, error) {
+	keyFile := path.Join(dataDir, identityKeyFile)
+	raw, err := os.ReadFile(keyFile)
+	if err == nil {
+		seed, err := hex.DecodeString(strings.TrimSpace(string(raw)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid identity key in %s", keyFile)
+		}
+		return crypto.GeneratePrivateKeyFromSeed(seed), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key := crypto.GeneratePrivateKey()
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyFile, []byte(hex.EncodeToString(key.Seed())), 0o600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// identityOf returns the identity of the node owning publicKey, which is how peers are keyed.
+func identityOf(publicKey []byte) string {
+	return hex.EncodeToString(publicKey)
+}
+
+func newNonce() []byte {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	return nonce
+}
+
+// handshakeDigest covers the whole message, including the challenge it answers: signing it proves
+// ownership of the identity key to whoever issued that challenge.
+func handshakeDigest(msg *proto.HandshakeMsg) []byte {
+	unsigned := pb.Clone(msg).(*proto.HandshakeMsg)
+	unsigned.Signature = nil
+	b, err := pb.MarshalOptions{Deterministic: true}.Marshal(unsigned)
+	if err != nil {
+		panic(err)
+	}
+
+	hash := sha256.Sum256(b)
+	return hash[:]
+}
+
+// signHandshake answers challenge with msg and asks the receiver to prove its own identity by signing a fresh nonce.
+func signHandshake(msg *proto.HandshakeMsg, challenge []byte, key *crypto.PrivateKey) {
+	msg.PublicKey = key.Public().Bytes()
+	msg.Nonce = newNonce()
+	msg.Challenge = challenge
+	msg.Signature = key.Sign(handshakeDigest(msg)).Bytes()
+}
+
+func verifyHandshake(msg *proto.HandshakeMsg) error {
+	publicKey, err := crypto.PublicKeyFromBytes(msg.PublicKey)
+	if err != nil {
+		return err
+	}
+	signature, err := crypto.SignatureFromBytes(msg.Signature)
+	if err != nil {
+		return err
+	}
+	if len(msg.Nonce) != nonceSize {
+		return fmt.Errorf("invalid nonce size %d", len(msg.Nonce))
+	}
+
+	if !signature.Verify(publicKey, handshakeDigest(msg)) {
+		return fmt.Errorf("handshake signature does not match identity %s", identityOf(msg.PublicKey))
+	}
+	return nil
+}
+
+// challenges tracks the nonces handed out to dialing peers, each of which can be answered only once. The
+// nonces outstanding are bounded, per remote host and overall, since anyone can ask for them.
+type challenges struct {
+	mu        sync.Mutex
+	clock     Clock
+	issued    map[string]*challenge
+	perRemote map[string]int
+}
+
+type challenge struct {
+	remote  string
+	expires time.Time
+}
+
+func newChallenges(clock Clock) *challenges {
+	return &challenges{clock: clock, issued: map[string]*challenge{}, perRemote: map[string]int{}}
+}
+
+// issue hands a nonce out to remote, unless too many nonces are outstanding for it or overall.
+func (c *challenges) issue(remote string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	for nonce, issued := range c.issued {
+		if now.After(issued.expires) {
+			c.remove(nonce)
+		}
+	}
+	if len(c.issued) >= maxChallenges {
+		return nil, errors.New("too many outstanding challenges")
+	}
+	if c.perRemote[remote] >= maxChallengesPerRemote {
+		return nil, fmt.Errorf("too many outstanding challenges for %s", remote)
+	}
+
+	nonce := newNonce()
+	c.issued[string(nonce)] = &challenge{remote: remote, expires: now.Add(challengeExpiry)}
+	c.perRemote[remote]++
+	return nonce, nil
+}
+
+func (c *challenges) consume(nonce []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	issued, ok := c.issued[string(nonce)]
+	if !ok {
+		return false
+	}
+	c.remove(string(nonce))
+	return c.clock.Now().Before(issued.expires)
+}
+
+func (c *challenges) remove(nonce string) {
+	remote := c.issued[nonce].remote
+	delete(c.issued, nonce)
+	if c.perRemote[remote]--; c.perRemote[remote] <= 0 {
+		delete(c.perRemote, remote)
+	}
+}
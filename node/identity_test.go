@@ -0,0 +1,173 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	pb "google.golang.org/protobuf/proto"
+)
+
+func TestIdentityIsPersistent(t *testing.T) {
+	dir := t.TempDir()
+	first, err := loadOrCreateIdentity(dir)
+	assert.NoError(t, err)
+
+	second, err := loadOrCreateIdentity(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, first.Bytes(), second.Bytes())
+}
+
+func TestSignedHandshake(t *testing.T) {
+	key := crypto.GeneratePrivateKey()
+	challenge := newNonce()
+	msg := &proto.HandshakeMsg{Version: ProtocolVersion, Address: "localhost:3000"}
+	signHandshake(msg, challenge, key)
+
+	assert.NoError(t, verifyHandshake(msg))
+	assert.Equal(t, challenge, msg.Challenge)
+	assert.Equal(t, identityOf(key.Public().Bytes()), identityOf(msg.PublicKey))
+
+	msg.Address = "localhost:3001"
+	assert.Error(t, verifyHandshake(msg))
+}
+
+func TestSignedHandshakeWithForeignKey(t *testing.T) {
+	msg := &proto.HandshakeMsg{Address: "localhost:3000"}
+	signHandshake(msg, newNonce(), crypto.GeneratePrivateKey())
+	msg.PublicKey = crypto.GeneratePrivateKey().Public().Bytes()
+
+	assert.Error(t, verifyHandshake(msg))
+}
+
+func TestSignedHandshakeMalformed(t *testing.T) {
+	assert.Error(t, verifyHandshake(&proto.HandshakeMsg{}))
+	assert.Error(t, verifyHandshake(&proto.HandshakeMsg{PublicKey: make([]byte, 3), Signature: make([]byte, 64)}))
+}
+
+func TestChallengesCanBeAnsweredOnce(t *testing.T) {
+	c := newChallenges(systemClock{})
+	nonce, err := c.issue("10.0.0.1")
+	assert.NoError(t, err)
+
+	assert.True(t, c.consume(nonce))
+	assert.False(t, c.consume(nonce))
+	assert.False(t, c.consume(newNonce()))
+}
+
+func TestChallengesExpire(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := newChallenges(clock)
+	fresh, _ := c.issue("10.0.0.1")
+	stale, _ := c.issue("10.0.0.1")
+
+	clock.Advance(challengeExpiry - time.Second)
+	assert.True(t, c.consume(fresh))
+	clock.Advance(2 * time.Second)
+	assert.False(t, c.consume(stale))
+}
+
+func TestChallengesAreBounded(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	c := newChallenges(clock)
+	nonces := [][]byte{}
+	for i := 0; i < maxChallengesPerRemote; i++ {
+		nonce, err := c.issue("10.0.0.1")
+		assert.NoError(t, err)
+		nonces = append(nonces, nonce)
+	}
+	_, err := c.issue("10.0.0.1")
+	assert.Error(t, err)
+	assert.True(t, c.consume(nonces[0]))
+	_, err = c.issue("10.0.0.1")
+	assert.NoError(t, err)
+
+	for i := 0; len(c.issued) < maxChallenges; i++ {
+		_, err := c.issue(fmt.Sprintf("10.1.%d.%d", i/256, i%256))
+		assert.NoError(t, err)
+	}
+	_, err = c.issue("10.0.0.2")
+	assert.Error(t, err)
+
+	clock.Advance(challengeExpiry + time.Second)
+	_, err = c.issue("10.0.0.1")
+	assert.NoError(t, err)
+	assert.Len(t, c.issued, 1)
+	assert.Len(t, c.perRemote, 1)
+}
+
+func TestHandshakeRequiresChallenge(t *testing.T) {
+	n := New()
+	n.identity = crypto.GeneratePrivateKey()
+	n.id = identityOf(n.identity.Public().Bytes())
+
+	msg := &proto.HandshakeMsg{Address: "localhost:3000"}
+	signHandshake(msg, newNonce(), crypto.GeneratePrivateKey())
+	_, err := n.Handshake(context.Background(), msg)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	challenge, err := n.Challenge(context.Background(), &proto.ChallengeMsg{})
+	assert.NoError(t, err)
+	msg = &proto.HandshakeMsg{Address: "localhost:3000"}
+	signHandshake(msg, challenge.Nonce, n.identity)
+	_, err = n.Handshake(context.Background(), msg)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestHandshakeRecordsAddressesFromTheConnection(t *testing.T) {
+	config := DefaultConfig()
+	config.Transport = NewMemoryTransport()
+	n := NewWithConfig(config)
+	n.identity = crypto.GeneratePrivateKey()
+	n.id = identityOf(n.identity.Public().Bytes())
+	n.addrBook = NewAddrBook(t.TempDir())
+
+	challenge, err := n.Challenge(context.Background(), &proto.ChallengeMsg{})
+	assert.NoError(t, err)
+	msg := &proto.HandshakeMsg{Version: ProtocolVersion, Address: "10.9.9.9:3000", KnownPeers: []string{"10.8.8.8:3000"}}
+	signHandshake(msg, challenge.Nonce, crypto.GeneratePrivateKey())
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5555}})
+	_, err = n.Handshake(ctx, msg)
+	assert.NoError(t, err)
+
+	n.addrBook.mu.Lock()
+	defer n.addrBook.mu.Unlock()
+	assert.Equal(t, "10.1.2.3:5555", n.addrBook.addresses["10.9.9.9:3000"].Source)
+	assert.Equal(t, "10.1.2.3:5555", n.addrBook.addresses["10.8.8.8:3000"].Source)
+}
+
+func TestHandshakeRejectsUndialableAddress(t *testing.T) {
+	config := DefaultConfig()
+	config.Transport = NewMemoryTransport()
+	n := NewWithConfig(config)
+	n.identity = crypto.GeneratePrivateKey()
+	n.id = identityOf(n.identity.Public().Bytes())
+
+	challenge, err := n.Challenge(context.Background(), &proto.ChallengeMsg{})
+	assert.NoError(t, err)
+	msg := &proto.HandshakeMsg{Version: ProtocolVersion, Address: "%zz"}
+	signHandshake(msg, challenge.Nonce, crypto.GeneratePrivateKey())
+	_, err = n.Handshake(context.Background(), msg)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Empty(t, n.GetPeers())
+}
+
+func FuzzVerifyHandshake(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg := &proto.HandshakeMsg{}
+		if err := pb.Unmarshal(data, msg); err != nil {
+			return
+		}
+		if verifyHandshake(msg) == nil {
+			assert.Len(t, identityOf(msg.PublicKey), 64)
+		}
+	})
+}
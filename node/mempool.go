@@ -0,0 +1,241 @@
+package node
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const defaultMempoolSize = 5000
+
+// Reasons given with EventMempoolEvict.
+const (
+	EvictIncluded = "included"
+	EvictConflict = "conflict"
+	EvictFull     = "full"
+	EvictInvalid  = "invalid"
+)
+
+type mempoolEntry struct {
+	tx   *proto.Transaction
+	seq  uint64
+	size int
+}
+
+// Mempool holds the valid transactions not yet included in a block. Two transactions spending the same
+// output are never held together: the first one seen wins.
+type Mempool struct {
+	mu      sync.RWMutex
+	txs     map[string]*mempoolEntry
+	spends  map[string]string
+	maxSize int
+	seq     uint64
+	// bytes is the serialized size of the pooled transactions.
+	bytes  int
+	events *EventBus
+}
+
+func NewMempool(maxSize int, events *EventBus) *Mempool {
+	return &Mempool{
+		txs:     map[string]*mempoolEntry{},
+		spends:  map[string]string{},
+		maxSize: maxSize,
+		events:  events,
+	}
+}
+
+// Add adds tx to the pool, evicting the oldest transaction when the pool is full. It returns false when
+// tx is already in the pool.
+func (m *Mempool) Add(tx *proto.Transaction) (bool, error) {
+	hash := types.HashTransactionSHA256(tx)
+	key := hex.EncodeToString(hash)
+
+	m.mu.Lock()
+	if _, ok := m.txs[key]; ok {
+		m.mu.Unlock()
+		return false, nil
+	}
+	for _, input := range tx.Inputs {
+		if other, ok := m.spends[outpointKey(input.PreviousTxHash, input.PrevOutputIndex)]; ok {
+			m.mu.Unlock()
+			return false, fmt.Errorf("transaction conflicts with %s", other)
+		}
+	}
+
+	evicted := []*mempoolEntry{}
+	for len(m.txs) >= m.maxSize && len(m.txs) > 0 {
+		evicted = append(evicted, m.remove(m.oldest()))
+	}
+	m.seq++
+	entry := &mempoolEntry{tx: tx, seq: m.seq, size: pb.Size(tx)}
+	m.txs[key] = entry
+	m.bytes += entry.size
+	for _, input := range tx.Inputs {
+		m.spends[outpointKey(input.PreviousTxHash, input.PrevOutputIndex)] = key
+	}
+	m.mu.Unlock()
+
+	for _, entry := range evicted {
+		m.events.Publish(Event{Kind: EventMempoolEvict, Transaction: entry.tx, Reason: EvictFull})
+	}
+	m.events.Publish(Event{Kind: EventMempoolAccept, Transaction: tx})
+	return true, nil
+}
+
+func (m *Mempool) oldest() string {
+	oldest := ""
+	for key, entry := range m.txs {
+		if oldest == "" || entry.seq < m.txs[oldest].seq {
+			oldest = key
+		}
+	}
+	return oldest
+}
+
+func (m *Mempool) remove(key string) *mempoolEntry {
+	entry := m.txs[key]
+	delete(m.txs, key)
+	m.bytes -= entry.size
+	for _, input := range entry.tx.Inputs {
+		delete(m.spends, outpointKey(input.PreviousTxHash, input.PrevOutputIndex))
+	}
+	return entry
+}
+
+// removeBlock drops the transactions included in block and the ones spending the same outputs.
+func (m *Mempool) removeBlock(block *proto.Block) {
+	type eviction struct {
+		tx     *proto.Transaction
+		reason string
+	}
+	evicted := []eviction{}
+
+	m.mu.Lock()
+	for _, tx := range block.Transaction {
+		key := hex.EncodeToString(types.HashTransactionSHA256(tx))
+		if _, ok := m.txs[key]; ok {
+			evicted = append(evicted, eviction{m.remove(key).tx, EvictIncluded})
+		}
+		for _, input := range tx.Inputs {
+			if other, ok := m.spends[outpointKey(input.PreviousTxHash, input.PrevOutputIndex)]; ok {
+				evicted = append(evicted, eviction{m.remove(other).tx, EvictConflict})
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	for _, e := range evicted {
+		m.events.Publish(Event{Kind: EventMempoolEvict, Transaction: e.tx, Reason: e.reason})
+	}
+}
+
+// removeInvalid drops the transactions for which valid returns false.
+func (m *Mempool) removeInvalid(valid func(*proto.Transaction) bool) {
+	evicted := []*mempoolEntry{}
+
+	m.mu.Lock()
+	for key, entry := range m.txs {
+		if !valid(entry.tx) {
+			evicted = append(evicted, m.remove(key))
+		}
+	}
+	m.mu.Unlock()
+
+	for _, entry := range evicted {
+		m.events.Publish(Event{Kind: EventMempoolEvict, Transaction: entry.tx, Reason: EvictInvalid})
+	}
+}
+
+func (m *Mempool) Has(hash []byte) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.txs[hex.EncodeToString(hash)]
+	return ok
+}
+
+func (m *Mempool) Size() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.txs)
+}
+
+// Bytes returns the serialized size of the pooled transactions.
+func (m *Mempool) Bytes() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.bytes
+}
+
+// Transactions returns the pooled transactions, oldest first.
+func (m *Mempool) Transactions() []*proto.Transaction {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := make([]*mempoolEntry, 0, len(m.txs))
+	for _, entry := range m.txs {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+
+	txs := make([]*proto.Transaction, len(entries))
+	for i, entry := range entries {
+		txs[i] = entry.tx
+	}
+	return txs
+}
+
+// updateMempool keeps the mempool consistent with the main chain: transactions are dropped once included
+// and put back when a reorg disconnects the block including them, while the ones spending outputs that left
+// the main chain are dropped. The subscription drops events when the mempool falls behind, so after a loss
+// the whole mempool is checked again against the UTXO set: this drops the transactions included in the
+// blocks missed, while the transactions of blocks disconnected by a missed reorg are lost.
+func (n *Node) updateMempool(sub *Subscription) {
+	var dropped uint64
+	for event := range sub.C {
+		switch event.Kind {
+		case EventNewTip:
+			n.mempool.removeBlock(event.Block)
+		case EventReorg:
+			for _, block := range event.Connected {
+				n.mempool.removeBlock(block)
+			}
+			n.mempool.removeInvalid(func(tx *proto.Transaction) bool {
+				return validateTransaction(tx, n.chain.utxos) == nil
+			})
+			for i := len(event.Disconnected) - 1; i >= 0; i-- {
+				for _, tx := range event.Disconnected[i].Transaction {
+					if validateTransaction(tx, n.chain.utxos) == nil {
+						n.mempool.Add(tx)
+					}
+				}
+			}
+		}
+		if sub.Dropped() > dropped {
+			dropped = sub.Dropped()
+			n.mempoolLogger.Warnf("missed chain events, checking the whole mempool again")
+			n.mempool.removeInvalid(func(tx *proto.Transaction) bool {
+				return validateTransaction(tx, n.chain.utxos) == nil
+			})
+		}
+	}
+}
+
+func (n *Node) relayTransaction(tx *proto.Transaction) {
+	n.peers.Range(func(_, value interface{}) bool {
+		peer := value.(*addPeerData)
+		ctx, cancel := n.withTimeout(pingTimeout)
+		defer cancel()
+		if _, err := (*peer.client).HandleTransaction(ctx, tx); err != nil {
+			n.mempoolLogger.Debugf("failed to relay transaction to %s: %v", peer.data.Address, err)
+		}
+		return true
+	})
+}
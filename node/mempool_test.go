@@ -0,0 +1,211 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTransaction(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	alice := crypto.GeneratePrivateKey()
+	bob := crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 100)
+	addTestBlock(t, c, mint)
+	pay := func(amount int64) *proto.TxOutput {
+		return &proto.TxOutput{Amount: amount, DestAddress: bob.Public().Address().Bytes()}
+	}
+
+	assert.NoError(t, validateTransaction(utils.SpendTransaction(alice, mint, 0, pay(100)), c.utxos))
+	assert.Error(t, validateTransaction(utils.MintTransaction(bob.Public().Address(), 1), c.utxos))
+	assert.Error(t, validateTransaction(utils.SpendTransaction(alice, mint, 0, pay(101)), c.utxos))
+	assert.Error(t, validateTransaction(utils.SpendTransaction(alice, mint, 0, pay(0)), c.utxos))
+	assert.Error(t, validateTransaction(utils.SpendTransaction(alice, mint, 1, pay(10)), c.utxos))
+	assert.Error(t, validateTransaction(utils.SpendTransaction(bob, mint, 0, pay(10)), c.utxos))
+
+	tampered := utils.SpendTransaction(alice, mint, 0, pay(10))
+	tampered.Outputs[0].Amount = 20
+	assert.Error(t, validateTransaction(tampered, c.utxos))
+}
+
+func TestMempoolAddAndConflicts(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(10)
+	m := NewMempool(10, bus)
+	alice := crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 100)
+	tx := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 10, DestAddress: alice.Public().Address().Bytes()})
+	double := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 20, DestAddress: alice.Public().Address().Bytes()})
+
+	added, err := m.Add(tx)
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, err = m.Add(tx)
+	assert.NoError(t, err)
+	assert.False(t, added)
+	_, err = m.Add(double)
+	assert.Error(t, err)
+
+	assert.Equal(t, 1, m.Size())
+	assert.True(t, m.Has(types.HashTransactionSHA256(tx)))
+	assert.Len(t, sub.C, 1)
+	assert.Equal(t, EventMempoolAccept, (<-sub.C).Kind)
+}
+
+func TestMempoolEvictsOldestWhenFull(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(10, EventMempoolEvict)
+	m := NewMempool(2, bus)
+	alice := crypto.GeneratePrivateKey()
+	txs := []*proto.Transaction{}
+	for i := int64(1); i <= 3; i++ {
+		mint := utils.MintTransaction(alice.Public().Address(), i)
+		tx := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: i, DestAddress: alice.Public().Address().Bytes()})
+		_, err := m.Add(tx)
+		assert.NoError(t, err)
+		txs = append(txs, tx)
+	}
+
+	assert.Equal(t, txs[1:], m.Transactions())
+	event := <-sub.C
+	assert.Equal(t, txs[0], event.Transaction)
+	assert.Equal(t, EvictFull, event.Reason)
+}
+
+func TestMempoolRemoveBlock(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(10, EventMempoolEvict)
+	m := NewMempool(10, bus)
+	alice := crypto.GeneratePrivateKey()
+	bob := crypto.GeneratePrivateKey()
+	mint1 := utils.MintTransaction(alice.Public().Address(), 10)
+	mint2 := utils.MintTransaction(alice.Public().Address(), 20)
+	included := utils.SpendTransaction(alice, mint1, 0, &proto.TxOutput{Amount: 10, DestAddress: bob.Public().Address().Bytes()})
+	pooled := utils.SpendTransaction(alice, mint2, 0, &proto.TxOutput{Amount: 20, DestAddress: bob.Public().Address().Bytes()})
+	conflicting := utils.SpendTransaction(alice, mint2, 0, &proto.TxOutput{Amount: 5, DestAddress: alice.Public().Address().Bytes()})
+	m.Add(included)
+	m.Add(pooled)
+
+	m.removeBlock(&proto.Block{Transaction: []*proto.Transaction{included, conflicting}})
+
+	assert.Equal(t, 0, m.Size())
+	assert.Equal(t, EvictIncluded, (<-sub.C).Reason)
+	assert.Equal(t, EvictConflict, (<-sub.C).Reason)
+}
+
+func TestMempoolFollowsReorgs(t *testing.T) {
+	n := NewWithConfig(DefaultConfig())
+	n.logger = logrus.New()
+	alice := crypto.GeneratePrivateKey()
+	genesis, _ := n.chain.GetBlockByHeight(0)
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	base := utils.ChildBlock(t, genesis, mint)
+	assert.NoError(t, n.chain.AddBlock(base))
+	tx := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 10, DestAddress: alice.Public().Address().Bytes()})
+	sub := n.events.Subscribe(10, EventMempoolAccept, EventMempoolEvict)
+
+	_, err := n.HandleTransaction(context.Background(), tx)
+	assert.NoError(t, err)
+	assert.Equal(t, EventMempoolAccept, (<-sub.C).Kind)
+
+	a2 := utils.ChildBlock(t, base, tx)
+	assert.NoError(t, n.chain.AddBlock(a2))
+	assert.Equal(t, EvictIncluded, (<-sub.C).Reason)
+
+	b2 := utils.ChildBlock(t, base)
+	assert.NoError(t, n.chain.AddBlock(b2))
+	assert.NoError(t, n.chain.AddBlock(utils.ChildBlock(t, b2)))
+	assert.Equal(t, EventMempoolAccept, (<-sub.C).Kind)
+	assert.True(t, n.mempool.Has(types.HashTransactionSHA256(tx)))
+}
+
+func TestRelayTimesOutOnTheNodeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	config := DefaultConfig()
+	config.Clock = clock
+	n := NewWithConfig(config)
+	n.peers.Store("hung", hungPeer(t))
+
+	waiters := clock.Waiters()
+	done := make(chan struct{})
+	go func() {
+		n.relayTransaction(utils.UnsignedTransactions(1, 1)[0])
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return clock.Waiters() == waiters+1 }, time.Second, time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("relay ended before its timeout")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(pingTimeout)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay did not time out")
+	}
+}
+
+func TestMempoolResyncsAfterMissedEvents(t *testing.T) {
+	n := NewWithConfig(DefaultConfig())
+	// the chain publishes to a bus only the subscription under test listens to
+	n.chain.events = NewEventBus()
+	sub := n.chain.events.Subscribe(1, EventNewTip, EventReorg)
+	defer sub.Close()
+	alice := crypto.GeneratePrivateKey()
+	genesis, _ := n.chain.GetBlockByHeight(0)
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	base := utils.ChildBlock(t, genesis, mint)
+	assert.NoError(t, n.chain.AddBlock(base))
+	tx := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 10, DestAddress: alice.Public().Address().Bytes()})
+	_, err := n.mempool.Add(tx)
+	assert.NoError(t, err)
+
+	// the block including tx comes while the subscription is full, so its event is lost
+	assert.NoError(t, n.chain.AddBlock(utils.ChildBlock(t, base, tx)))
+	assert.Equal(t, uint64(1), sub.Dropped())
+
+	go n.updateMempool(sub)
+	assert.Eventually(t, func() bool { return n.mempool.Size() == 0 }, time.Second, time.Millisecond)
+}
+
+func BenchmarkMempoolAdd(b *testing.B) {
+	m := NewMempool(b.N, NewEventBus())
+	txs := utils.UnsignedTransactions(b.N, 1)
+	b.ResetTimer()
+	for _, tx := range txs {
+		if _, err := m.Add(tx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMempoolAddEvict(b *testing.B) {
+	for _, size := range []int{100, 1000, defaultMempoolSize} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			m := NewMempool(size, NewEventBus())
+			for _, tx := range utils.UnsignedTransactions(size, 1) {
+				m.Add(tx)
+			}
+			txs := utils.UnsignedTransactions(b.N, 1)
+			for _, tx := range txs {
+				tx.Inputs[0].PrevOutputIndex += int32(size)
+			}
+			b.ResetTimer()
+			for _, tx := range txs {
+				if _, err := m.Add(tx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const metricsNamespace = "blockchain"
+
+// metrics holds the collectors updated as the node runs. Values that can be read from the node state at any
+// time, like the chain height or the mempool size, are collected on scrape instead.
+type metrics struct {
+	registry           *prometheus.Registry
+	blockConnect       prometheus.Histogram
+	validationFailures *prometheus.CounterVec
+	rpcs               *prometheus.CounterVec
+	rpcDuration        *prometheus.HistogramVec
+}
+
+func newMetrics(n *Node) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		blockConnect: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "block_connect_seconds",
+			Help:      "Time taken to add a block received from a peer to the chain.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}),
+		validationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "validation_failures_total",
+			Help:      "Transactions and blocks rejected, by reason.",
+		}, []string{"reason"}),
+		rpcs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "rpc_requests_total",
+			Help:      "RPCs served, by method and status code.",
+		}, []string{"method", "code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "rpc_duration_seconds",
+			Help:      "Time taken to serve RPCs, by method. Streams are measured until they end.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+	}
+
+	m.registry.MustRegister(
+		m.blockConnect,
+		m.validationFailures,
+		m.rpcs,
+		m.rpcDuration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "chain_height",
+			Help:      "Height of the tip of the main chain.",
+		}, func() float64 { return float64(n.chain.Height()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "mempool_transactions",
+			Help:      "Transactions in the mempool.",
+		}, func() float64 { return float64(n.mempool.Size()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "mempool_bytes",
+			Help:      "Serialized size of the transactions in the mempool.",
+		}, func() float64 { return float64(n.mempool.Bytes()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "orphan_blocks",
+			Help:      "Blocks waiting in the orphan pool for their parent.",
+		}, func() float64 { return float64(n.orphans.size()) }),
+		&peerCollector{node: n},
+	)
+	return m
+}
+
+func (m *metrics) observeBlockConnect(start time.Time) {
+	m.blockConnect.Observe(time.Since(start).Seconds())
+}
+
+func (m *metrics) validationFailure(reason string) {
+	m.validationFailures.WithLabelValues(reason).Inc()
+}
+
+// invalid counts err under its reason when it is a ValidationError, as transactions and blocks fail validation.
+func (m *metrics) invalid(err error) {
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		m.validationFailure(invalid.Reason)
+	}
+}
+
+func (m *metrics) observeRPC(method string, start time.Time, err error) {
+	m.rpcs.WithLabelValues(method, status.Code(err).String()).Inc()
+	m.rpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+func (m *metrics) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	m.observeRPC(info.FullMethod, start, err)
+	return resp, err
+}
+
+func (m *metrics) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	m.observeRPC(info.FullMethod, start, err)
+	return err
+}
+
+var (
+	peersDesc = prometheus.NewDesc(metricsNamespace+"_peers",
+		"Connected peers, by direction.", []string{"direction"}, nil)
+	peerBytesSentDesc = prometheus.NewDesc(metricsNamespace+"_peer_sent_bytes_total",
+		"Bytes sent to a connected peer.", []string{"peer"}, nil)
+	peerBytesReceivedDesc = prometheus.NewDesc(metricsNamespace+"_peer_received_bytes_total",
+		"Bytes received from a connected peer.", []string{"peer"}, nil)
+)
+
+// peerCollector reports the connected peers when scraped, so that peers leave the metrics as they disconnect.
+type peerCollector struct {
+	node *Node
+}
+
+func (c *peerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- peersDesc
+	ch <- peerBytesSentDesc
+	ch <- peerBytesReceivedDesc
+}
+
+func (c *peerCollector) Collect(ch chan<- prometheus.Metric) {
+	inbound, outbound := 0, 0
+	c.node.peers.Range(func(_, value interface{}) bool {
+		peer := value.(*addPeerData)
+		if peer.inbound {
+			inbound++
+		} else {
+			outbound++
+		}
+		ch <- prometheus.MustNewConstMetric(peerBytesSentDesc, prometheus.CounterValue, float64(peer.stats.sent.Load()), peer.id())
+		ch <- prometheus.MustNewConstMetric(peerBytesReceivedDesc, prometheus.CounterValue, float64(peer.stats.received.Load()), peer.id())
+		return true
+	})
+	ch <- prometheus.MustNewConstMetric(peersDesc, prometheus.GaugeValue, float64(inbound), "inbound")
+	ch <- prometheus.MustNewConstMetric(peersDesc, prometheus.GaugeValue, float64(outbound), "outbound")
+}
+
+// serveMetrics serves the metrics in the Prometheus text format at /metrics on config.MetricsListenAddr.
+func (n *Node) serveMetrics() {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(n.metrics.registry, promhttp.HandlerOpts{}))
+
+	n.rpcLogger.Infof("Metrics server started on %s", n.config.MetricsListenAddr)
+	if err := http.ListenAndServe(n.config.MetricsListenAddr, mux); err != nil {
+		n.rpcLogger.Fatalf("failed to serve metrics: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package node
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	pb "google.golang.org/protobuf/proto"
+)
+
+func TestMetrics(t *testing.T) {
+	n := NewWithConfig(DefaultConfig())
+	n.logger = logrus.New()
+	alice := crypto.GeneratePrivateKey()
+	genesis, _ := n.chain.GetBlockByHeight(0)
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	assert.NoError(t, n.chain.AddBlock(utils.ChildBlock(t, genesis, mint)))
+
+	tx := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 10, DestAddress: alice.Public().Address().Bytes()})
+	assert.NoError(t, n.submitTransaction(tx))
+	assert.Error(t, n.submitTransaction(utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 11, DestAddress: alice.Public().Address().Bytes()})))
+	assert.Error(t, n.submitTransaction(utils.MintTransaction(alice.Public().Address(), 1)))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(n.metrics.validationFailures.WithLabelValues(InvalidOverspend)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(n.metrics.validationFailures.WithLabelValues(InvalidStructure)))
+	assert.Equal(t, pb.Size(tx), n.mempool.Bytes())
+
+	forged := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 9, DestAddress: alice.Public().Address().Bytes()})
+	forged.Inputs[0].Signature[0] ^= 1
+	tip, _ := n.chain.GetBlockByHeight(1)
+	assert.Error(t, n.addBlock(utils.ChildBlock(t, tip, forged)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(n.metrics.validationFailures.WithLabelValues(InvalidSignature)))
+
+	recorder := httptest.NewRecorder()
+	promhttp.HandlerFor(n.metrics.registry, promhttp.HandlerOpts{}).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	assert.Contains(t, body, "blockchain_chain_height 1\n")
+	assert.Contains(t, body, "blockchain_mempool_transactions 1\n")
+	assert.Contains(t, body, `blockchain_peers{direction="inbound"} 0`)
+	assert.True(t, strings.HasPrefix(body, "# HELP"))
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	m := newMetrics(NewWithConfig(DefaultConfig()))
+	info := &grpc.UnaryServerInfo{FullMethod: "/Query/GetTip"}
+
+	m.unaryInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.rpcs.WithLabelValues("/Query/GetTip", "OK")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.rpcDuration))
+}
@@ -1,57 +1,90 @@
 package node
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	// "sync"
 
+	"github.com/fabrizioperria/blockchain/crypto"
 	"github.com/fabrizioperria/blockchain/logging"
 	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const addrBookSaveInterval = 30 * time.Second
+
 type nodeData struct {
 	version string
-	height  int32
-}
-
-type addPeerData struct {
-	client *proto.NodeClient
-	data   *proto.HandshakeMsg
 }
 
 type Node struct {
 	proto.UnimplementedNodeServer
-	peers        sync.Map
-	logger       *logrus.Logger
-	addPeerCh    chan *addPeerData
-	removePeerCh chan string
-	getPeersCh   chan chan []string
-	version      string
-	listenAddr   string
-	id           string
-	height       int32
+	config        *Config
+	transport     Transport
+	clock         Clock
+	chain         *Chain
+	mempool       *Mempool
+	events        *EventBus
+	orphans       *orphanPool
+	metrics       *metrics
+	peers         sync.Map
+	bans          *banList
+	addrBook      *AddrBook
+	credentials   *transportCredentials
+	identity      *crypto.PrivateKey
+	challenges    *challenges
+	loggers       *logging.Loggers
+	logger        *logrus.Logger
+	chainLogger   *logrus.Logger
+	mempoolLogger *logrus.Logger
+	rpcLogger     *logrus.Logger
+	addPeerCh     chan *addPeerData
+	removePeerCh  chan string
+	getPeersCh    chan chan []string
+	syncCh        chan struct{}
+	syncing       atomic.Bool
+	startedAt     time.Time
+	ready         chan struct{}
+	done          chan struct{}
+	stopOnce      sync.Once
+	version       string
+	listenAddr    string
+	id            string
 }
 
 func (n *Node) managePeers() {
 	for {
 		select {
 		case peer := <-n.removePeerCh:
-			n.peers.Delete(peer)
+			if data, ok := n.peers.LoadAndDelete(peer); ok {
+				data.(*addPeerData).conn.Close()
+				n.events.Publish(Event{Kind: EventPeerDisconnected, Peer: data.(*addPeerData).info()})
+			}
 		case data := <-n.addPeerCh:
 			if data.data.Address != "" {
-				n.peers.Store(data.data.Address, data)
+				n.peers.Store(identityOf(data.data.PublicKey), data)
+				n.events.Publish(Event{Kind: EventPeerConnected, Peer: data.info()})
+				// the sync loop looks for the best peer, so it can only start once the peer is stored
+				if data.data.Height > n.chain.Height() {
+					n.requestSync()
+				}
 			}
 		case res := <-n.getPeersCh:
 			peers := []string{}
 			n.peers.Range(func(key, value interface{}) bool {
-				peers = append(peers, key.(string))
+				peers = append(peers, value.(*addPeerData).data.Address)
 				return true
 			})
 			res <- peers
@@ -60,48 +93,177 @@ func (n *Node) managePeers() {
 }
 
 func New() *Node {
+	return NewWithConfig(DefaultConfig())
+}
+
+func NewWithConfig(config *Config) *Node {
 	d := getNodeData()
+	events := NewEventBus()
+	mempoolSize := config.MempoolSize
+	if mempoolSize <= 0 {
+		mempoolSize = defaultMempoolSize
+	}
+	clock := config.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
 
 	n := &Node{
+		config:       config,
+		clock:        clock,
+		chain:        NewChain(NewMemoryBlockStorer()),
+		mempool:      NewMempool(mempoolSize, events),
+		events:       events,
+		orphans:      newOrphanPool(clock, maxOrphans, maxOrphansPerPeer),
+		bans:         newBanList(clock),
+		credentials:  insecureCredentials(),
+		challenges:   newChallenges(clock),
 		version:      d.version,
-		height:       d.height,
 		peers:        sync.Map{},
 		addPeerCh:    make(chan *addPeerData, 100),
 		removePeerCh: make(chan string, 100),
 		getPeersCh:   make(chan chan []string, 100),
+		syncCh:       make(chan struct{}, 1),
+		ready:        make(chan struct{}),
+		done:         make(chan struct{}),
 	}
+	n.transport = config.Transport
+	if n.transport == nil {
+		n.transport = tcpTransport{}
+	}
+	if config.TxIndex {
+		n.chain.EnableTxIndex()
+	}
+	if config.AddressIndex {
+		n.chain.EnableAddrIndex()
+	}
+	n.chain.events = events
+	n.chain.verified = newSigCache(mempoolSize)
+	n.metrics = newMetrics(n)
+	n.setLoggers(config.Log)
 	go n.managePeers()
+	go n.updateMempool(events.Subscribe(subscriptionBufferSize, EventNewTip, EventReorg))
 
 	return n
 }
 
+// setLoggers creates the component loggers. They log to stdout until Start opens the log file, and fall back
+// to the default configuration when config is invalid.
+func (n *Node) setLoggers(config logging.Config) {
+	config.File = ""
+	loggers, err := logging.New(config)
+	if err != nil {
+		loggers, _ = logging.New(logging.Config{})
+	}
+	n.loggers = loggers
+	n.logger = loggers.Logger(logging.ComponentP2P)
+	n.chainLogger = loggers.Logger(logging.ComponentChain)
+	n.mempoolLogger = loggers.Logger(logging.ComponentMempool)
+	n.rpcLogger = loggers.Logger(logging.ComponentRPC)
+	if err != nil {
+		n.logger.Errorf("invalid log configuration: %v", err)
+	}
+}
+
 func getNodeData() *nodeData {
 	return &nodeData{
-		version: "1.0.0",
-		height:  100,
+		version: ProtocolVersion,
 	}
 }
 
 func (n *Node) Start(listenAddr string, bootstrapNodes []string) {
+	n.startedAt = n.clock.Now()
 	n.listenAddr = listenAddr
-	n.logger = logging.LoggerFactory("logs/" + strings.ReplaceAll(listenAddr, ":", "") + ".log")
-	opts := []grpc.ServerOption{}
+	logFile := n.config.Log.File
+	if logFile == "" {
+		logFile = "logs/" + strings.ReplaceAll(listenAddr, ":", "") + ".log"
+	}
+	if err := n.loggers.SetFile(logFile); err != nil {
+		n.logger.Fatalf("failed to open log file: %v", err)
+	}
+	stopReopening := n.loggers.ReopenOnSIGHUP()
+	defer stopReopening()
+	dataDir := n.config.dataDir(listenAddr)
+	identity, err := loadOrCreateIdentity(dataDir)
+	if err != nil {
+		n.logger.Fatalf("failed to load identity: %v", err)
+	}
+	n.identity = identity
+	n.id = identityOf(identity.Public().Bytes())
+	n.logger.Infof("Node identity %s", n.id)
+
+	n.addrBook = NewAddrBook(dataDir)
+	n.addrBook.clock = n.clock
+	if err := n.addrBook.Load(); err != nil {
+		n.logger.Warnf("failed to load address book: %v", err)
+	}
+
+	credentials, err := loadTransportCredentials(n.config.TLS, dataDir, listenAddr)
+	if err != nil {
+		n.logger.Fatalf("failed to set up TLS: %v", err)
+	}
+	n.credentials = credentials
+	if credentials.fingerprint != "" {
+		n.logger.Infof("TLS enabled, certificate fingerprint %s", credentials.fingerprint)
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.Creds(n.credentials.server),
+		grpc.ChainUnaryInterceptor(n.metrics.unaryInterceptor, n.authorizeAdmin),
+		grpc.ChainStreamInterceptor(n.metrics.streamInterceptor),
+	}
 	grpcServer := grpc.NewServer(opts...)
 
-	listener, err := net.Listen("tcp", listenAddr)
+	listener, err := n.transport.Listen(listenAddr)
 	if err != nil {
 		n.logger.Fatalf("failed to listen: %v", err)
 	}
 
 	proto.RegisterNodeServer(grpcServer, n)
+	proto.RegisterQueryServer(grpcServer, n.queryServer())
+	if n.config.AdminListenAddr == "" {
+		proto.RegisterAdminServer(grpcServer, &adminServer{node: n})
+	} else {
+		go n.serveAdmin()
+	}
+	if n.config.HTTPListenAddr != "" {
+		go n.serveGateway()
+	}
+	if n.config.MetricsListenAddr != "" {
+		go n.serveMetrics()
+	}
+
+	go n.syncLoop()
+	go n.pingPeers()
 
 	if err := n.bootstrapConnect(bootstrapNodes); err != nil {
 		n.logger.Fatalf("failed to connect to bootstrap nodes: %v", err)
 	}
+	go n.fillPeers()
+	go n.saveAddrBook()
 
 	n.logger.Infof("Server started on %s", listenAddr)
 
+	close(n.ready)
+	go func() {
+		<-n.done
+		grpcServer.Stop()
+	}()
 	grpcServer.Serve(listener)
+	if err := n.addrBook.Save(); err != nil {
+		n.logger.Errorf("failed to save address book: %v", err)
+	}
+	n.logger.Infof("Server stopped on %s", listenAddr)
+}
+
+// Ready is closed once the node listens for peers and has connected to its bootstrap nodes.
+func (n *Node) Ready() <-chan struct{} {
+	return n.ready
+}
+
+// Stop shuts the node down: Start returns once the server is stopped.
+func (n *Node) Stop() {
+	n.stopOnce.Do(func() { close(n.done) })
 }
 
 func (n *Node) bootstrapConnect(addresses []string) error {
@@ -115,43 +277,101 @@ func (n *Node) bootstrapConnect(addresses []string) error {
 			"from":    n.listenAddr,
 			"address": address,
 		}).Info("Bootstrapping to address")
+		n.addrBook.AddAddress(address, n.listenAddr)
 		client, msg, err := n.dialRemote(address)
 		if err != nil {
 			return err
 		}
-		n.addPeer(client, msg)
+		n.addPeer(client, msg, false)
 	}
 
 	return nil
 }
 
 func (n *Node) HandleTransaction(ctx context.Context, transaction *proto.Transaction) (*proto.Ack, error) {
-	n.logger.Info("Received transaction")
-	n.logger.WithFields(logrus.Fields{
-		"version": transaction.Version,
-	}).Info("Transaction received")
+	if err := n.submitTransaction(transaction); err != nil {
+		return nil, err
+	}
 	return &proto.Ack{}, nil
 }
 
+// submitTransaction adds transaction to the mempool and relays it, unless it was already there.
+func (n *Node) submitTransaction(transaction *proto.Transaction) error {
+	if err := validateTransaction(transaction, n.chain.utxos); err != nil {
+		n.metrics.invalid(err)
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	added, err := n.mempool.Add(transaction)
+	if err != nil {
+		n.metrics.validationFailure(InvalidConflict)
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	if added {
+		n.chain.verified.add(types.HashTransactionSHA256(transaction))
+		n.mempoolLogger.WithFields(logrus.Fields{
+			"hash": hex.EncodeToString(types.HashTransactionSHA256(transaction)),
+		}).Info("Transaction accepted")
+		go n.relayTransaction(transaction)
+	}
+	return nil
+}
+
+func (n *Node) Challenge(ctx context.Context, _ *proto.ChallengeMsg) (*proto.ChallengeMsg, error) {
+	nonce, err := n.challenges.issue(remoteHost(ctx))
+	if err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return &proto.ChallengeMsg{Nonce: nonce}, nil
+}
+
 func (n *Node) Handshake(ctx context.Context, helo *proto.HandshakeMsg) (*proto.HandshakeMsg, error) {
-	if n.hasConnectedTo(helo.Address) {
-		return nil, nil
+	if !n.challenges.consume(helo.Challenge) {
+		return nil, status.Error(codes.Unauthenticated, "unknown or expired challenge")
+	}
+	if err := verifyHandshake(helo); err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	id := identityOf(helo.PublicKey)
+	if id == n.id {
+		return nil, status.Error(codes.InvalidArgument, "cannot connect to self")
+	}
+	if err := checkCompatibility(helo, n.config.RequiredServices); err != nil {
+		n.logger.WithFields(logrus.Fields{
+			"address":  helo.Address,
+			"identity": id,
+		}).Infof("Rejected incompatible peer: %v", err)
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if n.bans.isBanned(helo.Address, id) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s is banned", id)
 	}
-	client, err := makeNodeClient(helo.Address)
+	if n.hasIdentity(id) || n.hasConnectedTo(helo.Address) {
+		return nil, status.Errorf(codes.AlreadyExists, "already connected to %s", id)
+	}
+	client, err := n.makeNodeClient(helo.Address)
 	if err != nil {
-		n.logger.Fatalf("failed to dial server: %v", err)
-		return nil, err
+		return nil, status.Errorf(codes.InvalidArgument, "cannot dial %s: %v", helo.Address, err)
 	}
 
-	myMsg := &proto.HandshakeMsg{
+	// the peer picks the address it claims, but not the one it connects from
+	client.remote = remoteAddress(ctx)
+
+	myMsg := n.handshakeMsg()
+	signHandshake(myMsg, helo.Nonce, n.identity)
+	n.addPeer(client, helo, true)
+
+	return myMsg, nil
+}
+
+func (n *Node) handshakeMsg() *proto.HandshakeMsg {
+	return &proto.HandshakeMsg{
 		Version:    n.version,
-		Height:     n.height,
+		Height:     n.chain.Height(),
 		Address:    n.listenAddr,
 		KnownPeers: n.GetPeers(),
+		MinVersion: MinProtocolVersion,
+		Services:   uint64(n.config.Services),
 	}
-	n.addPeer(&client, helo)
-
-	return myMsg, nil
 }
 
 func (n *Node) GetPeers() []string {
@@ -160,41 +380,126 @@ func (n *Node) GetPeers() []string {
 	return <-res
 }
 
-func (n *Node) addPeer(peer *proto.NodeClient, data *proto.HandshakeMsg) bool {
-	if n.hasConnectedTo(data.Address) {
+func (n *Node) addPeer(peer *nodeClient, data *proto.HandshakeMsg, inbound bool) bool {
+	if n.hasIdentity(identityOf(data.PublicKey)) || n.hasConnectedTo(data.Address) {
+		peer.conn.Close()
 		return false
 	}
-	n.addPeerCh <- &addPeerData{client: peer, data: data}
+	p := &addPeerData{
+		client:      &peer.client,
+		conn:        peer.conn,
+		stats:       peer.stats,
+		data:        data,
+		inbound:     inbound,
+		connectedAt: n.clock.Now(),
+	}
+	n.addPeerCh <- p
 	n.logger.WithFields(logrus.Fields{
 		"receiver":     n.listenAddr,
 		"addedPeer":    data.Address,
+		"identity":     identityOf(data.PublicKey),
 		"theirVersion": data.Version,
+		"services":     ServiceFlag(data.Services),
 		"theirHeight":  data.Height,
+		"inbound":      inbound,
 	}).Info("Added peer")
 
-	go n.bootstrapConnect(data.KnownPeers)
+	go n.pingPeer(p)
+
+	// the addresses the peer tells about are bucketed by where it connects from
+	n.addrBook.AddAddress(data.Address, peer.remote)
+	for _, address := range data.KnownPeers {
+		n.addrBook.AddAddress(address, peer.remote)
+	}
+	go n.fillPeers()
 
 	return true
 }
 
-func (n *Node) dialRemote(address string) (*proto.NodeClient, *proto.HandshakeMsg, error) {
+// fillPeers dials addresses drawn from the address book until the node has enough peers.
+func (n *Node) fillPeers() {
+	missing := n.config.MaxPeers - len(n.GetPeers())
+	if missing <= 0 {
+		return
+	}
+
+	skip := func(address string) bool {
+		return n.hasConnectedTo(address) || n.bans.isBanned(address, "")
+	}
+	for _, address := range n.addrBook.Select(missing, skip) {
+		client, msg, err := n.dialRemote(address)
+		if err != nil {
+			n.logger.WithFields(logrus.Fields{
+				"address": address,
+			}).Debugf("failed to connect to peer: %v", err)
+			continue
+		}
+		n.addPeer(client, msg, false)
+	}
+}
+
+func (n *Node) saveAddrBook() {
+	ticker := n.clock.NewTicker(addrBookSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			if err := n.addrBook.Save(); err != nil {
+				n.logger.Errorf("failed to save address book: %v", err)
+			}
+		case <-n.done:
+			return
+		}
+	}
+}
+
+func (n *Node) dialRemote(address string) (*nodeClient, *proto.HandshakeMsg, error) {
 	if address == n.listenAddr {
 		return nil, nil, fmt.Errorf("cannot connect to self")
 	}
-	client, err := makeNodeClient(address)
+	client, err := n.makeNodeClient(address)
 	if err != nil {
 		return nil, nil, err
 	}
-	msg, err := client.Handshake(context.Background(), &proto.HandshakeMsg{
-		Version:    n.version,
-		Height:     n.height,
-		Address:    n.listenAddr,
-		KnownPeers: n.GetPeers(),
-	})
+	msg, err := n.handshake(client, address)
 	if err != nil {
+		client.conn.Close()
 		return nil, nil, err
 	}
-	return &client, msg, nil
+	return client, msg, nil
+}
+
+func (n *Node) handshake(client *nodeClient, address string) (*proto.HandshakeMsg, error) {
+	n.addrBook.MarkAttempt(address)
+	challenge, err := client.client.Challenge(context.Background(), &proto.ChallengeMsg{})
+	if err != nil {
+		return nil, err
+	}
+
+	helo := n.handshakeMsg()
+	signHandshake(helo, challenge.Nonce, n.identity)
+	msg, err := client.client.Handshake(context.Background(), helo)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(msg.Challenge, helo.Nonce) {
+		return nil, fmt.Errorf("peer %s did not answer our challenge", address)
+	}
+	if err := verifyHandshake(msg); err != nil {
+		return nil, err
+	}
+	id := identityOf(msg.PublicKey)
+	if id == n.id {
+		return nil, fmt.Errorf("cannot connect to self")
+	}
+	if n.bans.isBanned(address, id) {
+		return nil, fmt.Errorf("peer %s is banned", id)
+	}
+	if err := checkCompatibility(msg, n.config.RequiredServices); err != nil {
+		return nil, fmt.Errorf("incompatible peer %s: %w", address, err)
+	}
+	n.addrBook.MarkGood(address)
+	return msg, nil
 }
 
 func (n *Node) hasConnectedTo(address string) bool {
@@ -202,21 +507,75 @@ func (n *Node) hasConnectedTo(address string) bool {
 		return true
 	}
 
-	_, ok := n.peers.Load(address)
+	found := false
+	n.peers.Range(func(_, value interface{}) bool {
+		found = value.(*addPeerData).data.Address == address
+		return !found
+	})
+	return found
+}
+
+func (n *Node) hasIdentity(id string) bool {
+	if id == n.id {
+		return true
+	}
+
+	_, ok := n.peers.Load(id)
 	return ok
 }
 
-func (n *Node) removePeer(peer string) {
-	n.removePeerCh <- peer
+// findPeer looks a peer up by identity or by address.
+func (n *Node) findPeer(target string) *addPeerData {
+	if data, ok := n.peers.Load(target); ok {
+		return data.(*addPeerData)
+	}
+
+	var found *addPeerData
+	n.peers.Range(func(_, value interface{}) bool {
+		if peer := value.(*addPeerData); peer.data.Address == target {
+			found = peer
+		}
+		return found == nil
+	})
+	return found
+}
+
+func (n *Node) peersWithService(flag ServiceFlag) []*addPeerData {
+	peers := []*addPeerData{}
+	n.peers.Range(func(_, value interface{}) bool {
+		if peer := value.(*addPeerData); peer.hasService(flag) {
+			peers = append(peers, peer)
+		}
+		return true
+	})
+	return peers
+}
+
+func (n *Node) removePeer(id string) {
+	n.removePeerCh <- id
 	n.logger.WithFields(logrus.Fields{
-		"address": peer,
+		"identity": id,
 	}).Info("Removed peer")
 }
 
-func makeNodeClient(listenAddr string) (proto.NodeClient, error) {
-	client, err := grpc.NewClient(listenAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func (n *Node) serveAdmin() {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(n.metrics.unaryInterceptor, n.authorizeAdmin))
+	proto.RegisterAdminServer(grpcServer, &adminServer{node: n})
+
+	listener, err := net.Listen("tcp", n.config.AdminListenAddr)
 	if err != nil {
-		return nil, err
+		n.rpcLogger.Fatalf("failed to listen for admin: %v", err)
+	}
+	n.rpcLogger.Infof("Admin server started on %s", n.config.AdminListenAddr)
+
+	grpcServer.Serve(listener)
+}
+
+func (n *Node) queryServer() *queryServer {
+	return &queryServer{
+		chain:   n.chain,
+		events:  n.events,
+		mempool: n.mempool,
+		submit:  n.submitTransaction,
 	}
-	return proto.NewNodeClient(client), nil
 }
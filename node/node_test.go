@@ -1,35 +1,145 @@
 package node
 
 import (
+	"context"
+	"net"
 	"strconv"
 	"testing"
 	"time"
 
-	// "time"
-
+	proto "github.com/fabrizioperria/blockchain/protobuf"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func TestSetupCluster(t *testing.T) {
+	transport := NewMemoryTransport()
+	newNode := func() *Node {
+		config := DefaultConfig()
+		config.DataDir = t.TempDir()
+		config.Transport = transport
+		return NewWithConfig(config)
+	}
+
 	n := []*Node{}
-	n = append(n, makeNode("localhost:3000", []string{}))
+	n = append(n, makeNodeWithInstance(newNode(), "localhost:3000", []string{}))
 	expectedNumPeers := 9
 	for i := 0; i < expectedNumPeers; i++ {
 		port := 3001 + i
-		n = append(n, makeNode("localhost:"+strconv.Itoa(port), []string{"localhost:3000"}))
+		n = append(n, makeNodeWithInstance(newNode(), "localhost:"+strconv.Itoa(port), []string{"localhost:3000"}))
 	}
 
-	time.Sleep(1 * time.Second)
-	for _, node := range n {
-		l := len(node.GetPeers())
-		assert.Equal(t, expectedNumPeers, l, "expected %d peers, got %d", expectedNumPeers, l)
+	for i, node := range n {
+		assert.Eventually(t, func() bool { return len(node.GetPeers()) == expectedNumPeers }, 5*time.Second, time.Millisecond,
+			"node %d has %d peers, expected %d", i, len(node.GetPeers()), expectedNumPeers)
 	}
 }
 
-func makeNode(listenAddr string, bootstrapNodes []string) *Node {
-	n := New()
+func TestMemoryTransport(t *testing.T) {
+	transport := NewMemoryTransport()
+	listener, err := transport.Listen("a")
+	assert.NoError(t, err)
+	_, err = transport.Listen("a")
+	assert.Error(t, err)
+
+	_, err = transport.Dial(context.Background(), "b")
+	assert.Error(t, err)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := transport.Dial(context.Background(), "a")
+	assert.NoError(t, err)
+	conn.Close()
+
+	listener.Close()
+	_, err = transport.Dial(context.Background(), "a")
+	assert.Error(t, err)
+	_, err = transport.Listen("a")
+	assert.NoError(t, err)
+}
+
+// makeNodeWithInstance starts n and waits until it is ready.
+func makeNodeWithInstance(n *Node, listenAddr string, bootstrapNodes []string) *Node {
 	go n.Start(listenAddr, bootstrapNodes)
-	time.Sleep(1 * time.Second)
+	select {
+	case <-n.Ready():
+	case <-time.After(5 * time.Second):
+		panic("node " + listenAddr + " did not start")
+	}
 
 	return n
 }
+
+func TestStopEndsStart(t *testing.T) {
+	config := DefaultConfig()
+	config.DataDir = t.TempDir()
+	config.Transport = NewSimNetwork(1).Transport("sim:0")
+	n := NewWithConfig(config)
+	stopped := make(chan struct{})
+	go func() {
+		n.Start("sim:0", nil)
+		close(stopped)
+	}()
+	<-n.Ready()
+	n.addrBook.AddAddress("10.0.0.1:3000", "10.0.0.2:3000")
+
+	n.Stop()
+	n.Stop()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return")
+	}
+	saved := NewAddrBook(config.DataDir)
+	assert.NoError(t, saved.Load())
+	assert.Equal(t, 1, saved.Size())
+}
+
+// hungPeer returns a peer whose connection never completes, so that the calls to it only end with their
+// timeout.
+func hungPeer(t *testing.T) *addPeerData {
+	conn, err := grpc.NewClient("passthrough:///hung",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	client := proto.NewNodeClient(conn)
+	return &addPeerData{client: &client, conn: conn, data: &proto.HandshakeMsg{Address: "hung"}}
+}
+
+func TestPingTimesOutOnTheNodeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	config := DefaultConfig()
+	config.Clock = clock
+	n := NewWithConfig(config)
+	peer := hungPeer(t)
+
+	waiters := clock.Waiters()
+	done := make(chan struct{})
+	go func() {
+		n.pingPeer(peer)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return clock.Waiters() == waiters+1 }, time.Second, time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("ping ended before its timeout")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(pingTimeout)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ping did not time out")
+	}
+	assert.Equal(t, int32(1), peer.failedPings.Load())
+	assert.Eventually(t, func() bool { return clock.Waiters() == waiters }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,145 @@
+package node
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+)
+
+const (
+	maxOrphans        = 500
+	maxOrphansPerPeer = 100
+	orphanExpiry      = 10 * time.Minute
+	// orphanParentWindow is the number of blocks below an orphan requested at once to find its parents.
+	orphanParentWindow = 16
+)
+
+type orphanBlock struct {
+	block   *proto.Block
+	hash    string
+	peer    string
+	expires time.Time
+}
+
+// orphanPool holds the blocks received before their parent, keyed by the hash of that parent, until the
+// parent joins the chain. Orphans expire, and each peer can only hold a share of the pool.
+type orphanPool struct {
+	mu         sync.Mutex
+	clock      Clock
+	byParent   map[string][]*orphanBlock
+	byHash     map[string]*orphanBlock
+	perPeer    map[string]int
+	maxSize    int
+	maxPerPeer int
+}
+
+func newOrphanPool(clock Clock, maxSize int, maxPerPeer int) *orphanPool {
+	return &orphanPool{
+		clock:      clock,
+		byParent:   map[string][]*orphanBlock{},
+		byHash:     map[string]*orphanBlock{},
+		perPeer:    map[string]int{},
+		maxSize:    maxSize,
+		maxPerPeer: maxPerPeer,
+	}
+}
+
+// add holds block, sent by peer, until its parent arrives. When the pool is full the orphan closest to
+// expiry makes room, while a peer holding its whole share cannot add more.
+func (p *orphanPool) add(block *proto.Block, peer string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	hash := hex.EncodeToString(types.HashBlockSHA256(block))
+	if _, ok := p.byHash[hash]; ok {
+		return nil
+	}
+	p.expire()
+	if p.perPeer[peer] >= p.maxPerPeer {
+		return fmt.Errorf("peer %s holds too many orphan blocks", peer)
+	}
+	for len(p.byHash) >= p.maxSize && len(p.byHash) > 0 {
+		p.remove(p.oldest())
+	}
+
+	orphan := &orphanBlock{block: block, hash: hash, peer: peer, expires: p.clock.Now().Add(orphanExpiry)}
+	parent := hex.EncodeToString(block.Header.PreviousHash)
+	p.byParent[parent] = append(p.byParent[parent], orphan)
+	p.byHash[hash] = orphan
+	p.perPeer[peer]++
+	return nil
+}
+
+func (p *orphanPool) has(hash []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, ok := p.byHash[hex.EncodeToString(hash)]
+	return ok
+}
+
+// takeChildren removes and returns the orphans whose parent is parentHash.
+func (p *orphanPool) takeChildren(parentHash []byte) []*proto.Block {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expire()
+	orphans := append([]*orphanBlock{}, p.byParent[hex.EncodeToString(parentHash)]...)
+	children := make([]*proto.Block, 0, len(orphans))
+	for _, orphan := range orphans {
+		children = append(children, orphan.block)
+		p.remove(orphan)
+	}
+	return children
+}
+
+func (p *orphanPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.byHash)
+}
+
+func (p *orphanPool) expire() {
+	now := p.clock.Now()
+	for _, orphan := range p.byHash {
+		if !now.Before(orphan.expires) {
+			p.remove(orphan)
+		}
+	}
+}
+
+func (p *orphanPool) oldest() *orphanBlock {
+	var oldest *orphanBlock
+	for _, orphan := range p.byHash {
+		if oldest == nil || orphan.expires.Before(oldest.expires) {
+			oldest = orphan
+		}
+	}
+	return oldest
+}
+
+func (p *orphanPool) remove(orphan *orphanBlock) {
+	delete(p.byHash, orphan.hash)
+	if p.perPeer[orphan.peer]--; p.perPeer[orphan.peer] <= 0 {
+		delete(p.perPeer, orphan.peer)
+	}
+
+	parent := hex.EncodeToString(orphan.block.Header.PreviousHash)
+	siblings := p.byParent[parent]
+	for i, sibling := range siblings {
+		if sibling == orphan {
+			siblings = append(siblings[:i], siblings[i+1:]...)
+			break
+		}
+	}
+	if len(siblings) == 0 {
+		delete(p.byParent, parent)
+	} else {
+		p.byParent[parent] = siblings
+	}
+}
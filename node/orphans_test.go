@@ -0,0 +1,79 @@
+package node
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrphanPoolTakesChildren(t *testing.T) {
+	p := newOrphanPool(NewFakeClock(time.Now()), 10, 10)
+	genesis, _ := NewChain(NewMemoryBlockStorer()).GetBlockByHeight(0)
+	blocks := utils.Branch(t, genesis, 2)
+	sibling := utils.ChildBlock(t, genesis)
+
+	assert.NoError(t, p.add(blocks[1], "a"))
+	assert.NoError(t, p.add(blocks[0], "a"))
+	assert.NoError(t, p.add(sibling, "b"))
+	assert.NoError(t, p.add(sibling, "b"))
+	assert.Equal(t, 3, p.size())
+	assert.True(t, p.has(types.HashBlockSHA256(blocks[0])))
+
+	assert.ElementsMatch(t, []*proto.Block{blocks[0], sibling}, p.takeChildren(types.HashBlockSHA256(genesis)))
+	assert.Empty(t, p.takeChildren(types.HashBlockSHA256(genesis)))
+	assert.Equal(t, []*proto.Block{blocks[1]}, p.takeChildren(types.HashBlockSHA256(blocks[0])))
+	assert.Equal(t, 0, p.size())
+}
+
+func TestOrphanPoolLimits(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	p := newOrphanPool(clock, 3, 2)
+	genesis, _ := NewChain(NewMemoryBlockStorer()).GetBlockByHeight(0)
+	blocks := utils.Branch(t, genesis, 5)
+
+	assert.NoError(t, p.add(blocks[0], "a"))
+	clock.Advance(time.Second)
+	assert.NoError(t, p.add(blocks[1], "a"))
+	assert.Error(t, p.add(blocks[2], "a"))
+
+	// a full pool drops its oldest orphan
+	assert.NoError(t, p.add(blocks[2], "b"))
+	assert.NoError(t, p.add(blocks[3], "b"))
+	assert.Equal(t, 3, p.size())
+	assert.False(t, p.has(types.HashBlockSHA256(blocks[0])))
+	assert.NoError(t, p.add(blocks[4], "a"))
+
+	clock.Advance(orphanExpiry)
+	assert.Empty(t, p.takeChildren(types.HashBlockSHA256(blocks[3])))
+	assert.Equal(t, 0, p.size())
+}
+
+func TestSyncFetchesParentsOfOrphans(t *testing.T) {
+	network := NewSimNetwork(1)
+	nodes := []*Node{}
+	for i, length := range []int{3, 2 * orphanParentWindow} {
+		address := "sim:" + strconv.Itoa(i)
+		config := DefaultConfig()
+		config.DataDir = t.TempDir()
+		config.Transport = network.Transport(address)
+		n := NewWithConfig(config)
+		genesis, _ := n.chain.GetBlockByHeight(0)
+		for _, block := range utils.Branch(t, genesis, length) {
+			assert.NoError(t, n.chain.AddBlock(block))
+		}
+		nodes = append(nodes, makeNodeWithInstance(n, address, []string{}))
+	}
+	a, b := nodes[0], nodes[1]
+
+	_, err := (&adminServer{node: a}).AddPeer(context.Background(), &proto.PeerTarget{Target: b.listenAddr})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return TipsConverged(a, b) }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, int32(2*orphanParentWindow), a.chain.Height())
+	assert.Equal(t, 0, a.orphans.size())
+}
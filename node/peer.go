@@ -0,0 +1,158 @@
+package node
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+const (
+	pingInterval   = 30 * time.Second
+	pingTimeout    = 5 * time.Second
+	maxFailedPings = 3
+)
+
+type addPeerData struct {
+	client      *proto.NodeClient
+	conn        *grpc.ClientConn
+	stats       *connStats
+	data        *proto.HandshakeMsg
+	inbound     bool
+	connectedAt time.Time
+	latency     atomic.Int64
+	failedPings atomic.Int32
+	// stalls counts the block requests this peer left unanswered for too long.
+	stalls atomic.Int32
+}
+
+func (p *addPeerData) id() string {
+	return identityOf(p.data.PublicKey)
+}
+
+// hasService must be checked before calling any RPC that only some peers serve.
+func (p *addPeerData) hasService(flag ServiceFlag) bool {
+	return ServiceFlag(p.data.Services).Has(flag)
+}
+
+func (p *addPeerData) info() *proto.PeerInfo {
+	return &proto.PeerInfo{
+		Identity:      p.id(),
+		Address:       p.data.Address,
+		Inbound:       p.inbound,
+		Version:       p.data.Version,
+		Services:      p.data.Services,
+		Height:        p.data.Height,
+		LatencyMicros: time.Duration(p.latency.Load()).Microseconds(),
+		BytesSent:     p.stats.sent.Load(),
+		BytesReceived: p.stats.received.Load(),
+		ConnectedAt:   p.connectedAt.Unix(),
+	}
+}
+
+// connStats counts the bytes exchanged over our client connection to a peer.
+type connStats struct {
+	sent     atomic.Uint64
+	received atomic.Uint64
+}
+
+func (s *connStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (s *connStats) HandleRPC(_ context.Context, rs stats.RPCStats) {
+	switch p := rs.(type) {
+	case *stats.OutPayload:
+		s.sent.Add(uint64(p.WireLength))
+	case *stats.InPayload:
+		s.received.Add(uint64(p.WireLength))
+	}
+}
+
+func (s *connStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (s *connStats) HandleConn(context.Context, stats.ConnStats) {}
+
+type nodeClient struct {
+	client proto.NodeClient
+	conn   *grpc.ClientConn
+	stats  *connStats
+	// remote is the address the peer connected from, or the one dialed to reach it.
+	remote string
+}
+
+func (n *Node) makeNodeClient(listenAddr string) (*nodeClient, error) {
+	s := &connStats{}
+	// passthrough hands the address to the transport as is, rather than resolving it first.
+	conn, err := grpc.NewClient("passthrough:///"+listenAddr,
+		grpc.WithContextDialer(n.transport.Dial),
+		grpc.WithTransportCredentials(n.credentials.client),
+		grpc.WithStatsHandler(s),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &nodeClient{client: proto.NewNodeClient(conn), conn: conn, stats: s, remote: listenAddr}, nil
+}
+
+func (n *Node) Ping(ctx context.Context, _ *proto.Ack) (*proto.Ack, error) {
+	return &proto.Ack{}, nil
+}
+
+// pingPeers measures the latency of every peer and drops the ones that stopped answering.
+func (n *Node) pingPeers() {
+	ticker := n.clock.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			n.peers.Range(func(_, value interface{}) bool {
+				go n.pingPeer(value.(*addPeerData))
+				return true
+			})
+		case <-n.done:
+			return
+		}
+	}
+}
+
+func (n *Node) pingPeer(peer *addPeerData) {
+	ctx, cancel := n.withTimeout(pingTimeout)
+	defer cancel()
+
+	start := n.clock.Now()
+	if _, err := (*peer.client).Ping(ctx, &proto.Ack{}); err != nil {
+		if peer.failedPings.Add(1) >= maxFailedPings {
+			n.logger.WithFields(logrus.Fields{
+				"address":  peer.data.Address,
+				"identity": peer.id(),
+			}).Warnf("Peer stopped answering: %v", err)
+			n.removePeer(peer.id())
+		}
+		return
+	}
+	peer.failedPings.Store(0)
+	peer.latency.Store(int64(n.clock.Since(start)))
+}
+
+// withTimeout is context.WithTimeout on the clock of the node. A ticker serves as the timer, since it can
+// be stopped once the context is done.
+func (n *Node) withTimeout(d time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ticker := n.clock.NewTicker(d)
+	go func() {
+		defer ticker.Stop()
+		select {
+		case <-ticker.C():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,290 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strconv"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type queryServer struct {
+	proto.UnimplementedQueryServer
+	chain   *Chain
+	events  *EventBus
+	mempool *Mempool
+	// submit validates a transaction, adds it to the mempool and relays it to the peers.
+	submit func(*proto.Transaction) error
+}
+
+func (q *queryServer) GetTip(ctx context.Context, _ *proto.Ack) (*proto.ChainTip, error) {
+	block, height, err := q.chain.Tip()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &proto.ChainTip{
+		Height:    height,
+		Hash:      types.HashBlockSHA256(block),
+		Timestamp: block.Header.Timestamp,
+	}, nil
+}
+
+func (q *queryServer) GetBlockByHash(ctx context.Context, req *proto.HashRequest) (*proto.Block, error) {
+	block, err := q.chain.GetBlockByHash(req.Hash)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return block, nil
+}
+
+func (q *queryServer) GetBlockByHeight(ctx context.Context, req *proto.HeightRequest) (*proto.Block, error) {
+	block, err := q.chain.GetBlockByHeight(req.Height)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return block, nil
+}
+
+// ListBlocks pages through the main chain from the tip down to the genesis block.
+func (q *queryServer) ListBlocks(ctx context.Context, req *proto.PageRequest) (*proto.BlockList, error) {
+	size, err := pageSize(req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	from := int(q.chain.Height())
+	if req.PageToken != "" {
+		if from, err = pageOffset(req.PageToken, from); err != nil {
+			return nil, err
+		}
+	}
+
+	list := &proto.BlockList{}
+	height := from
+	for ; height >= 0 && len(list.Blocks) < size; height-- {
+		block, err := q.chain.GetBlockByHeight(int32(height))
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		list.Blocks = append(list.Blocks, block)
+	}
+	if height >= 0 {
+		list.NextPageToken = strconv.Itoa(height)
+	}
+
+	return list, nil
+}
+
+func (q *queryServer) GetTransaction(ctx context.Context, req *proto.HashRequest) (*proto.TransactionInfo, error) {
+	info, err := q.chain.findTransaction(req.Hash)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if info == nil {
+		return nil, status.Errorf(codes.NotFound, "transaction %x not found", req.Hash)
+	}
+	return info, nil
+}
+
+func (q *queryServer) GetUTXOs(ctx context.Context, req *proto.AddressRequest) (*proto.UTXOList, error) {
+	address, err := crypto.AddressFromBytes(req.Address)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	size, err := pageSize(req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	utxos := q.chain.utxos.ByAddress(address.Bytes())
+	offset, err := pageOffset(req.PageToken, len(utxos))
+	if err != nil {
+		return nil, err
+	}
+
+	list := &proto.UTXOList{}
+	end := min(offset+size, len(utxos))
+	for _, utxo := range utxos[offset:end] {
+		list.Utxos = append(list.Utxos, utxo.toProto())
+	}
+	if end < len(utxos) {
+		list.NextPageToken = strconv.Itoa(end)
+	}
+
+	return list, nil
+}
+
+func (q *queryServer) GetBalance(ctx context.Context, req *proto.AddressRequest) (*proto.Balance, error) {
+	address, err := crypto.AddressFromBytes(req.Address)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &proto.Balance{
+		Address: address.Bytes(),
+		Amount:  q.chain.utxos.Balance(address.Bytes()),
+		Height:  q.chain.Height(),
+	}, nil
+}
+
+// GetAddressHistory pages through the transactions touching an address, newest first.
+func (q *queryServer) GetAddressHistory(ctx context.Context, req *proto.AddressRequest) (*proto.AddressHistory, error) {
+	if q.chain.addrIndex == nil {
+		return nil, status.Error(codes.FailedPrecondition, "address index is disabled")
+	}
+	address, err := crypto.AddressFromBytes(req.Address)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	size, err := pageSize(req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := pageOffset(req.PageToken, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, total := q.chain.addrIndex.History(address.Bytes(), offset, size)
+	history := &proto.AddressHistory{Entries: entries, Total: int32(total)}
+	if offset+len(entries) < total {
+		history.NextPageToken = strconv.Itoa(offset + len(entries))
+	}
+	return history, nil
+}
+
+func (q *queryServer) GetBalanceAtHeight(ctx context.Context, req *proto.BalanceRequest) (*proto.Balance, error) {
+	if q.chain.addrIndex == nil {
+		return nil, status.Error(codes.FailedPrecondition, "address index is disabled")
+	}
+	address, err := crypto.AddressFromBytes(req.Address)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.Height < 0 || req.Height > q.chain.Height() {
+		return nil, status.Errorf(codes.OutOfRange, "height %d is beyond the chain tip", req.Height)
+	}
+
+	return &proto.Balance{
+		Address: address.Bytes(),
+		Amount:  q.chain.addrIndex.BalanceAtHeight(address.Bytes(), req.Height),
+		Height:  req.Height,
+	}, nil
+}
+
+// GetMempool pages through the mempool, oldest transactions first.
+func (q *queryServer) GetMempool(ctx context.Context, req *proto.PageRequest) (*proto.MempoolList, error) {
+	if q.mempool == nil {
+		return nil, status.Error(codes.Unavailable, "mempool is not available")
+	}
+	size, err := pageSize(req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	txs := q.mempool.Transactions()
+	offset, err := pageOffset(req.PageToken, len(txs))
+	if err != nil {
+		return nil, err
+	}
+
+	list := &proto.MempoolList{Total: int32(len(txs))}
+	end := min(offset+size, len(txs))
+	for _, tx := range txs[offset:end] {
+		list.Transactions = append(list.Transactions, &proto.MempoolTransaction{
+			Hash:        types.HashTransactionSHA256(tx),
+			Transaction: tx,
+		})
+	}
+	if end < len(txs) {
+		list.NextPageToken = strconv.Itoa(end)
+	}
+
+	return list, nil
+}
+
+func (q *queryServer) SendTransaction(ctx context.Context, tx *proto.Transaction) (*proto.TxReceipt, error) {
+	if q.submit == nil {
+		return nil, status.Error(codes.Unavailable, "transaction submission is not available")
+	}
+	if err := q.submit(tx); err != nil {
+		return nil, err
+	}
+	return &proto.TxReceipt{TxHash: types.HashTransactionSHA256(tx)}, nil
+}
+
+// findTransaction looks the transaction up in the transaction index, or walks the main chain from the tip
+// when the index is disabled.
+func (c *Chain) findTransaction(hash []byte) (*proto.TransactionInfo, error) {
+	c.mu.RLock()
+	txIndex := c.txIndex
+	c.mu.RUnlock()
+	if txIndex != nil {
+		blockHash, index, ok := txIndex.Lookup(hash)
+		if !ok {
+			return nil, nil
+		}
+		block, err := c.GetBlockByHash(blockHash)
+		if err != nil {
+			return nil, err
+		}
+		height, _ := c.heightOf(blockHash)
+		return c.transactionInfo(block, height, index), nil
+	}
+
+	for height := c.Height(); height >= 0; height-- {
+		block, err := c.GetBlockByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+		for i, tx := range block.Transaction {
+			if bytes.Equal(types.HashTransactionSHA256(tx), hash) {
+				return c.transactionInfo(block, height, int32(i)), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (c *Chain) transactionInfo(block *proto.Block, height int32, index int32) *proto.TransactionInfo {
+	tx := block.Transaction[index]
+	return &proto.TransactionInfo{
+		Transaction:   tx,
+		Hash:          types.HashTransactionSHA256(tx),
+		BlockHash:     types.HashBlockSHA256(block),
+		BlockHeight:   height,
+		Index:         index,
+		Confirmations: c.Height() - height + 1,
+	}
+}
+
+func pageSize(requested int32) (int, error) {
+	switch {
+	case requested < 0:
+		return 0, status.Errorf(codes.InvalidArgument, "invalid page size %d", requested)
+	case requested == 0:
+		return defaultPageSize, nil
+	default:
+		return min(int(requested), maxPageSize), nil
+	}
+}
+
+func pageOffset(token string, limit int) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(token)
+	if err != nil || offset < 0 || offset > limit {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid page token %q", token)
+	}
+	return offset, nil
+}
@@ -0,0 +1,191 @@
+package node
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQueryBalancesAndUTXOs(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	q := &queryServer{chain: c}
+	alice := crypto.GeneratePrivateKey()
+	bob := crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 100)
+	addTestBlock(t, c, mint)
+	payment := utils.SpendTransaction(alice, mint, 0,
+		&proto.TxOutput{Amount: 30, DestAddress: bob.Public().Address().Bytes()},
+		&proto.TxOutput{Amount: 70, DestAddress: alice.Public().Address().Bytes()},
+	)
+	addTestBlock(t, c, payment)
+
+	ctx := context.Background()
+	balance, err := q.GetBalance(ctx, &proto.AddressRequest{Address: alice.Public().Address().Bytes()})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(70), balance.Amount)
+	assert.Equal(t, int32(2), balance.Height)
+
+	balance, err = q.GetBalance(ctx, &proto.AddressRequest{Address: bob.Public().Address().Bytes()})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(30), balance.Amount)
+
+	utxos, err := q.GetUTXOs(ctx, &proto.AddressRequest{Address: bob.Public().Address().Bytes()})
+	assert.NoError(t, err)
+	assert.Len(t, utxos.Utxos, 1)
+	assert.Equal(t, types.HashTransactionSHA256(payment), utxos.Utxos[0].TxHash)
+	assert.Equal(t, int32(0), utxos.Utxos[0].OutputIndex)
+	assert.Equal(t, int32(2), utxos.Utxos[0].Height)
+	assert.Empty(t, utxos.NextPageToken)
+
+	_, err = q.GetBalance(ctx, &proto.AddressRequest{Address: []byte{1, 2, 3}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestQueryUTXOsPagination(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	q := &queryServer{chain: c}
+	alice := crypto.GeneratePrivateKey().Public().Address()
+	for i := 0; i < 5; i++ {
+		addTestBlock(t, c, utils.MintTransaction(alice, int64(i+1)))
+	}
+
+	seen := []int64{}
+	token := ""
+	for {
+		page, err := q.GetUTXOs(context.Background(), &proto.AddressRequest{Address: alice.Bytes(), PageSize: 2, PageToken: token})
+		assert.NoError(t, err)
+		for _, utxo := range page.Utxos {
+			seen = append(seen, utxo.Output.Amount)
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		token = page.NextPageToken
+	}
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, seen)
+
+	_, err := q.GetUTXOs(context.Background(), &proto.AddressRequest{Address: alice.Bytes(), PageToken: "42"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestQueryTransaction(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	q := &queryServer{chain: c}
+	tx := utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 10)
+	block := addTestBlock(t, c, utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 5), tx)
+	addTestBlocks(t, c, 2)
+
+	info, err := q.GetTransaction(context.Background(), &proto.HashRequest{Hash: types.HashTransactionSHA256(tx)})
+	assert.NoError(t, err)
+	assert.Equal(t, types.HashBlockSHA256(block), info.BlockHash)
+	assert.Equal(t, int32(1), info.BlockHeight)
+	assert.Equal(t, int32(1), info.Index)
+	assert.Equal(t, int32(3), info.Confirmations)
+
+	_, err = q.GetTransaction(context.Background(), &proto.HashRequest{Hash: utils.RandomHash(t)})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// TestQueryTransactionConcurrentAccess looks transactions up while blocks keep joining the chain. Run with -race.
+func TestQueryTransactionConcurrentAccess(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	assert.NoError(t, c.EnableTxIndex())
+	q := &queryServer{chain: c}
+	tx := utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 10)
+	addTestBlock(t, c, tx)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				info, err := q.GetTransaction(context.Background(), &proto.HashRequest{Hash: types.HashTransactionSHA256(tx)})
+				assert.NoError(t, err)
+				assert.Equal(t, int32(1), info.BlockHeight)
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		addTestBlock(t, c, utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 10))
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestQueryBlocks(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	q := &queryServer{chain: c}
+	addTestBlocks(t, c, 4)
+	ctx := context.Background()
+
+	tip, err := q.GetTip(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), tip.Height)
+
+	block, err := q.GetBlockByHash(ctx, &proto.HashRequest{Hash: tip.Hash})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), block.Header.Height)
+	_, err = q.GetBlockByHeight(ctx, &proto.HeightRequest{Height: 5})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	page, err := q.ListBlocks(ctx, &proto.PageRequest{PageSize: 3})
+	assert.NoError(t, err)
+	assert.Len(t, page.Blocks, 3)
+	assert.Equal(t, int32(4), page.Blocks[0].Header.Height)
+	assert.Equal(t, "1", page.NextPageToken)
+
+	page, err = q.ListBlocks(ctx, &proto.PageRequest{PageSize: 3, PageToken: page.NextPageToken})
+	assert.NoError(t, err)
+	assert.Len(t, page.Blocks, 2)
+	assert.Equal(t, int32(0), page.Blocks[1].Header.Height)
+	assert.Empty(t, page.NextPageToken)
+
+	_, err = q.ListBlocks(ctx, &proto.PageRequest{PageSize: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestQueryMempoolAndSendTransaction(t *testing.T) {
+	n := NewWithConfig(DefaultConfig())
+	n.logger = logrus.New()
+	q := n.queryServer()
+	alice := crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 100)
+	addTestBlock(t, n.chain, mint)
+	ctx := context.Background()
+
+	txs := []*proto.Transaction{}
+	for i := int64(1); i <= 2; i++ {
+		tx := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: i, DestAddress: alice.Public().Address().Bytes()})
+		txs = append(txs, tx)
+	}
+	receipt, err := q.SendTransaction(ctx, txs[0])
+	assert.NoError(t, err)
+	assert.Equal(t, types.HashTransactionSHA256(txs[0]), receipt.TxHash)
+	_, err = q.SendTransaction(ctx, txs[1])
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = q.SendTransaction(ctx, utils.MintTransaction(alice.Public().Address(), 1))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	list, err := q.GetMempool(ctx, &proto.PageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), list.Total)
+	assert.Equal(t, receipt.TxHash, list.Transactions[0].Hash)
+	assert.Empty(t, list.NextPageToken)
+}
@@ -0,0 +1,114 @@
+package node
+
+import (
+	"encoding/hex"
+	"runtime"
+	"sync"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+)
+
+// sigCache holds the transactions whose signatures were verified when they entered the mempool, so that
+// connecting the block including them does not verify them again.
+type sigCache struct {
+	mu      sync.Mutex
+	hashes  map[string]struct{}
+	maxSize int
+}
+
+func newSigCache(maxSize int) *sigCache {
+	return &sigCache{hashes: map[string]struct{}{}, maxSize: maxSize}
+}
+
+// add records tx hash, forgetting an arbitrary transaction when the cache is full.
+func (c *sigCache) add(hash []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.hashes) >= c.maxSize {
+		for key := range c.hashes {
+			delete(c.hashes, key)
+			break
+		}
+	}
+	c.hashes[hex.EncodeToString(hash)] = struct{}{}
+}
+
+// take tells whether hash is in the cache and removes it: a transaction is only connected once.
+func (c *sigCache) take(hash []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := hex.EncodeToString(hash)
+	_, ok := c.hashes[key]
+	delete(c.hashes, key)
+	return ok
+}
+
+func (c *sigCache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.hashes)
+}
+
+type sigJob struct {
+	tx, input int
+	hash      []byte
+}
+
+// verifySignatures checks the input signatures of txs on up to workers goroutines, skipping the
+// transactions found in cache. It stops at the first invalid signature.
+func verifySignatures(txs []*proto.Transaction, cache *sigCache, workers int) error {
+	jobs := []sigJob{}
+	for i, tx := range txs {
+		if len(tx.Inputs) == 0 || cache.take(types.HashTransactionSHA256(tx)) {
+			continue
+		}
+		hash := types.HashTransactionForSigning(tx)
+		for j := range tx.Inputs {
+			jobs = append(jobs, sigJob{tx: i, input: j, hash: hash})
+		}
+	}
+	workers = min(workers, len(jobs))
+
+	var (
+		failure error
+		once    sync.Once
+		wg      sync.WaitGroup
+	)
+	failed := make(chan struct{})
+	queue := make(chan sigJob)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				if !types.VerifyInput(txs[job.tx].Inputs[job.input], job.hash) {
+					once.Do(func() {
+						failure = invalid(InvalidSignature, "transaction %d: input %d: invalid signature", job.tx, job.input)
+						close(failed)
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, job := range jobs {
+		select {
+		case queue <- job:
+		case <-failed:
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return failure
+}
+
+// verifyBlockSignatures verifies the signatures of block on GOMAXPROCS workers.
+func verifyBlockSignatures(block *proto.Block, cache *sigCache) error {
+	return verifySignatures(block.Transaction, cache, runtime.GOMAXPROCS(0))
+}
@@ -0,0 +1,74 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySignatures(t *testing.T) {
+	txs := utils.SignedTransactions(20, 2)
+	assert.NoError(t, verifySignatures(txs, newSigCache(10), 4))
+	assert.NoError(t, verifySignatures(nil, newSigCache(10), 4))
+
+	txs[13].Inputs[1].Signature = txs[12].Inputs[1].Signature
+	err := verifySignatures(txs, newSigCache(10), 4)
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, InvalidSignature, validationErr.Reason)
+	assert.EqualError(t, err, "transaction 13: input 1: invalid signature")
+}
+
+func TestVerifySignaturesSkipsCachedTransactions(t *testing.T) {
+	txs := utils.SignedTransactions(3, 2)
+	txs[1].Inputs[0].Signature = nil
+	cache := newSigCache(10)
+	cache.add(types.HashTransactionSHA256(txs[1]))
+
+	assert.NoError(t, verifySignatures(txs, cache, 2))
+	assert.Equal(t, 0, cache.size())
+	assert.Error(t, verifySignatures(txs, cache, 2))
+}
+
+func TestSigCacheIsBounded(t *testing.T) {
+	cache := newSigCache(2)
+	for _, tx := range utils.SignedTransactions(3, 2) {
+		cache.add(types.HashTransactionSHA256(tx))
+	}
+	assert.Equal(t, 2, cache.size())
+}
+
+func TestMempoolTransactionsAreNotVerifiedTwice(t *testing.T) {
+	n := NewWithConfig(DefaultConfig())
+	alice := crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	base := addTestBlock(t, n.chain, mint)
+	tx := utils.SpendTransaction(alice, mint, 0, &proto.TxOutput{Amount: 10, DestAddress: alice.Public().Address().Bytes()})
+
+	_, err := n.HandleTransaction(context.Background(), tx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n.chain.verified.size())
+
+	assert.NoError(t, n.chain.AddBlock(utils.ChildBlock(t, base, tx)))
+	assert.Equal(t, 0, n.chain.verified.size())
+}
+
+func BenchmarkVerifySignatures(b *testing.B) {
+	txs := utils.SignedTransactions(500, 2)
+	for _, workers := range []int{1, runtime.GOMAXPROCS(0)} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := verifySignatures(txs, newSigCache(1), workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
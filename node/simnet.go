@@ -0,0 +1,326 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fabrizioperria/blockchain/types"
+)
+
+// Link describes the conditions between two nodes of a SimNetwork, in both directions.
+type Link struct {
+	// Latency delays every write on the link. It is measured on the clock of the node writing, so that a
+	// FakeClock drives it too.
+	Latency time.Duration
+	// DropRate is the probability that a write is lost. A lost write behaves as a connection reset: the
+	// connection carrying it breaks, failing the RPCs in flight until gRPC reconnects. Connections are byte
+	// streams, so a single write cannot go missing without corrupting the ones following it.
+	DropRate float64
+}
+
+type linkKey struct {
+	a, b string
+}
+
+func newLinkKey(a, b string) linkKey {
+	if b < a {
+		a, b = b, a
+	}
+	return linkKey{a, b}
+}
+
+// SimNetwork runs nodes of the same process over simulated links whose latency, loss and partitions can be
+// changed while the nodes run. Random losses are drawn from a seeded source, so a seed replays the same
+// sequence of draws.
+type SimNetwork struct {
+	mu        sync.Mutex
+	listeners map[string]*simListener
+	links     map[linkKey]Link
+	// partition holds the group of every address while the network is partitioned, nil otherwise.
+	partition map[string]int
+	conns     map[*simConn]bool
+	rand      *rand.Rand
+	nodes     []*Node
+}
+
+func NewSimNetwork(seed int64) *SimNetwork {
+	return &SimNetwork{
+		listeners: map[string]*simListener{},
+		links:     map[linkKey]Link{},
+		conns:     map[*simConn]bool{},
+		rand:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Transport returns the transport of the node listening on address, which delays its writes on the system
+// clock.
+func (s *SimNetwork) Transport(address string) Transport {
+	return &simTransport{network: s, address: address, clock: systemClock{}}
+}
+
+// StartNode starts a node listening on address, with config and the simulated transport delaying its writes
+// on config.Clock, and waits until it is ready. Nodes must not share a DataDir, and bootstrapNodes must be
+// reachable since Start exits when it cannot connect to them.
+func (s *SimNetwork) StartNode(address string, config *Config, bootstrapNodes []string) *Node {
+	c := *config
+	clock := config.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	c.Transport = &simTransport{network: s, address: address, clock: clock}
+	n := NewWithConfig(&c)
+	go n.Start(address, bootstrapNodes)
+	<-n.Ready()
+
+	s.mu.Lock()
+	s.nodes = append(s.nodes, n)
+	s.mu.Unlock()
+	return n
+}
+
+// Nodes returns the nodes started by StartNode.
+func (s *SimNetwork) Nodes() []*Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*Node{}, s.nodes...)
+}
+
+// SetLink sets the conditions between the nodes listening on a and b.
+func (s *SimNetwork) SetLink(a, b string, link Link) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.links[newLinkKey(a, b)] = link
+}
+
+// SetLinks sets the conditions between every pair of addresses.
+func (s *SimNetwork) SetLinks(addresses []string, link Link) {
+	for i, a := range addresses {
+		for _, b := range addresses[i+1:] {
+			s.SetLink(a, b, link)
+		}
+	}
+}
+
+// Partition splits the network into groups: connections between addresses of different groups are cut and
+// cannot be made again until Heal. Addresses missing from every group form a group of their own.
+func (s *SimNetwork) Partition(groups ...[]string) {
+	s.mu.Lock()
+	s.partition = map[string]int{}
+	for i, group := range groups {
+		for _, address := range group {
+			s.partition[address] = i + 1
+		}
+	}
+	cut := []*simConn{}
+	for conn := range s.conns {
+		if !s.reachable(conn.from, conn.to) {
+			cut = append(cut, conn)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, conn := range cut {
+		conn.Close()
+	}
+}
+
+// Heal ends the partition.
+func (s *SimNetwork) Heal() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.partition = nil
+}
+
+// reachable must be called with s.mu held.
+func (s *SimNetwork) reachable(a, b string) bool {
+	return s.partition == nil || s.partition[a] == s.partition[b]
+}
+
+// conditions returns the link between a and b, and whether a write on it is lost.
+func (s *SimNetwork) conditions(a, b string) (Link, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link := s.links[newLinkKey(a, b)]
+	lost := !s.reachable(a, b) || link.DropRate > 0 && s.rand.Float64() < link.DropRate
+	return link, lost
+}
+
+// dial connects from to the node listening on to. The connection delays the writes of each end on the clock
+// of its node.
+func (s *SimNetwork) dial(ctx context.Context, from string, to string, clock Clock) (net.Conn, error) {
+	s.mu.Lock()
+	listener, ok := s.listeners[to]
+	if !ok || !s.reachable(from, to) {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("dial %s: connection refused", to)
+	}
+	local, remote := net.Pipe()
+	client := &simConn{Conn: local, network: s, clock: clock, from: from, to: to}
+	server := &simConn{Conn: remote, network: s, clock: listener.clock, from: to, to: from}
+	client.peer, server.peer = server, client
+	s.conns[client] = true
+	s.conns[server] = true
+	s.mu.Unlock()
+
+	select {
+	case listener.conns <- server:
+		return client, nil
+	case <-listener.done:
+	case <-ctx.Done():
+	}
+	client.Close()
+	return nil, fmt.Errorf("dial %s: connection refused", to)
+}
+
+func (s *SimNetwork) listen(address string, clock Clock) (net.Listener, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.listeners[address]; ok {
+		return nil, fmt.Errorf("address %s already in use", address)
+	}
+	listener := &simListener{network: s, address: address, clock: clock, conns: make(chan net.Conn), done: make(chan struct{})}
+	s.listeners[address] = listener
+	return listener, nil
+}
+
+type simTransport struct {
+	network *SimNetwork
+	address string
+	clock   Clock
+}
+
+func (t *simTransport) Listen(address string) (net.Listener, error) {
+	return t.network.listen(address, t.clock)
+}
+
+func (t *simTransport) Dial(ctx context.Context, address string) (net.Conn, error) {
+	return t.network.dial(ctx, t.address, address, t.clock)
+}
+
+type simAddr string
+
+func (a simAddr) Network() string { return "sim" }
+func (a simAddr) String() string  { return string(a) }
+
+type simListener struct {
+	network *SimNetwork
+	address string
+	clock   Clock
+	conns   chan net.Conn
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (l *simListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *simListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+		l.network.mu.Lock()
+		delete(l.network.listeners, l.address)
+		l.network.mu.Unlock()
+	})
+	return nil
+}
+
+func (l *simListener) Addr() net.Addr {
+	return simAddr(l.address)
+}
+
+// simConn is one end of a connection from the node listening on from to the one listening on to. Closing
+// either end breaks the connection.
+type simConn struct {
+	net.Conn
+	network *SimNetwork
+	clock   Clock
+	peer    *simConn
+	from    string
+	to      string
+	once    sync.Once
+}
+
+func (c *simConn) Write(p []byte) (int, error) {
+	link, lost := c.network.conditions(c.from, c.to)
+	if lost {
+		c.Close()
+		return 0, net.ErrClosed
+	}
+	if link.Latency > 0 {
+		<-c.clock.After(link.Latency)
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *simConn) Close() error {
+	c.once.Do(func() {
+		c.network.mu.Lock()
+		delete(c.network.conns, c)
+		delete(c.network.conns, c.peer)
+		c.network.mu.Unlock()
+		c.Conn.Close()
+		c.peer.Conn.Close()
+	})
+	return nil
+}
+
+func (c *simConn) LocalAddr() net.Addr {
+	return simAddr(c.from)
+}
+
+func (c *simConn) RemoteAddr() net.Addr {
+	return simAddr(c.to)
+}
+
+// TipsConverged reports whether nodes share the same main chain tip.
+func TipsConverged(nodes ...*Node) bool {
+	var tip []byte
+	for i, n := range nodes {
+		block, _, err := n.chain.Tip()
+		if err != nil {
+			return false
+		}
+		hash := types.HashBlockSHA256(block)
+		if i > 0 && !bytes.Equal(hash, tip) {
+			return false
+		}
+		tip = hash
+	}
+	return true
+}
+
+// PeersConverged reports whether every node is connected to all the others, and to no one else.
+func PeersConverged(nodes ...*Node) bool {
+	for _, n := range nodes {
+		expected := []string{}
+		for _, other := range nodes {
+			if other != n {
+				expected = append(expected, other.listenAddr)
+			}
+		}
+		peers := n.GetPeers()
+		sort.Strings(expected)
+		sort.Strings(peers)
+		if fmt.Sprint(peers) != fmt.Sprint(expected) {
+			return false
+		}
+	}
+	return true
+}
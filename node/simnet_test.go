@@ -0,0 +1,161 @@
+package node
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/stretchr/testify/assert"
+)
+
+func startSimCluster(t *testing.T, network *SimNetwork, count int) []*Node {
+	nodes := []*Node{}
+	for i := 0; i < count; i++ {
+		config := DefaultConfig()
+		config.DataDir = t.TempDir()
+		bootstrap := []string{}
+		if i > 0 {
+			bootstrap = []string{"sim:0"}
+		}
+		nodes = append(nodes, network.StartNode("sim:"+strconv.Itoa(i), config, bootstrap))
+	}
+	return nodes
+}
+
+// reconnect drops the connection between a and b on both sides, then has b dial a again. Peer exchange may
+// connect them again first, which is just as good.
+func reconnect(t *testing.T, a *Node, b *Node) {
+	ctx := context.Background()
+	(&adminServer{node: a}).RemovePeer(ctx, &proto.PeerTarget{Target: b.listenAddr})
+	(&adminServer{node: b}).RemovePeer(ctx, &proto.PeerTarget{Target: a.listenAddr})
+	assert.Eventually(t, func() bool {
+		return !a.hasConnectedTo(b.listenAddr) && !b.hasConnectedTo(a.listenAddr)
+	}, time.Second, time.Millisecond)
+	// the error of a dial racing with peer exchange only tells that a already knows b
+	(&adminServer{node: b}).AddPeer(ctx, &proto.PeerTarget{Target: a.listenAddr})
+	assert.Eventually(t, func() bool {
+		return a.hasConnectedTo(b.listenAddr) && b.hasConnectedTo(a.listenAddr)
+	}, time.Second, time.Millisecond)
+}
+
+func TestSimNetworkConvergesWithLatency(t *testing.T) {
+	network := NewSimNetwork(1)
+	network.SetLinks([]string{"sim:0", "sim:1", "sim:2", "sim:3", "sim:4"}, Link{Latency: time.Millisecond})
+
+	start := time.Now()
+	nodes := startSimCluster(t, network, 5)
+	assert.Eventually(t, func() bool { return PeersConverged(nodes...) }, 5*time.Second, time.Millisecond)
+	assert.Greater(t, time.Since(start), 5*time.Millisecond)
+	assert.True(t, TipsConverged(nodes...))
+}
+
+func TestSimNetworkPartitionAndHeal(t *testing.T) {
+	network := NewSimNetwork(1)
+	nodes := startSimCluster(t, network, 3)
+	a, b, c := nodes[0], nodes[1], nodes[2]
+	assert.Eventually(t, func() bool { return PeersConverged(nodes...) }, 5*time.Second, time.Millisecond)
+
+	network.Partition([]string{"sim:0"}, []string{"sim:1", "sim:2"})
+	_, _, err := b.dialRemote(a.listenAddr)
+	assert.Error(t, err)
+	_, err = (*b.findPeer(a.listenAddr).client).Ping(context.Background(), &proto.Ack{})
+	assert.Error(t, err)
+	_, err = (*b.findPeer(c.listenAddr).client).Ping(context.Background(), &proto.Ack{})
+	assert.NoError(t, err)
+
+	addTestBlocks(t, a.chain, 3)
+	assert.False(t, TipsConverged(nodes...))
+
+	network.Heal()
+	reconnect(t, a, b)
+	reconnect(t, a, c)
+	assert.Eventually(t, func() bool { return TipsConverged(nodes...) }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, int32(3), c.chain.Height())
+}
+
+func TestSimNetworkDropRate(t *testing.T) {
+	network := NewSimNetwork(1)
+	nodes := startSimCluster(t, network, 2)
+	assert.Eventually(t, func() bool { return PeersConverged(nodes...) }, 5*time.Second, time.Millisecond)
+	peer := nodes[1].findPeer("sim:0")
+
+	network.SetLink("sim:0", "sim:1", Link{DropRate: 1})
+	_, err := (*peer.client).Ping(context.Background(), &proto.Ack{})
+	assert.Error(t, err)
+
+	network.SetLink("sim:0", "sim:1", Link{})
+	assert.Eventually(t, func() bool {
+		_, err := (*peer.client).Ping(context.Background(), &proto.Ack{})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSimNetworkLatencyFollowsTheClock(t *testing.T) {
+	network := NewSimNetwork(1)
+	network.SetLink("sim:0", "sim:1", Link{Latency: time.Hour})
+	clock := NewFakeClock(time.Now())
+	listener, err := (&simTransport{network: network, address: "sim:0", clock: clock}).Listen("sim:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+	client, err := (&simTransport{network: network, address: "sim:1", clock: clock}).Dial(context.Background(), "sim:0")
+	assert.NoError(t, err)
+	server := <-accepted
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := client.Write([]byte("ping"))
+		written <- err
+	}()
+	assert.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	select {
+	case <-written:
+		t.Fatal("write went through before the latency elapsed")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Hour)
+	b := make([]byte, 4)
+	_, err = io.ReadFull(server, b)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(b))
+	assert.NoError(t, <-written)
+}
+
+func TestSimNetworkDropsUnresponsivePeers(t *testing.T) {
+	network := NewSimNetwork(1)
+	clock := NewFakeClock(time.Now())
+	nodes := []*Node{}
+	for i := 0; i < 2; i++ {
+		config := DefaultConfig()
+		config.DataDir = t.TempDir()
+		config.Clock = clock
+		bootstrap := []string{}
+		if i > 0 {
+			bootstrap = []string{"sim:0"}
+		}
+		nodes = append(nodes, network.StartNode("sim:"+strconv.Itoa(i), config, bootstrap))
+	}
+	a, b := nodes[0], nodes[1]
+	assert.Eventually(t, func() bool { return PeersConverged(nodes...) }, 5*time.Second, time.Millisecond)
+	// Both nodes ping their peers and save their address book on a ticker.
+	assert.Eventually(t, func() bool { return clock.Waiters() == 4 }, time.Second, time.Millisecond)
+
+	network.Partition([]string{"sim:0"}, []string{"sim:1"})
+	peer := b.findPeer(a.listenAddr)
+	for i := int32(1); i < maxFailedPings; i++ {
+		clock.Advance(pingInterval)
+		assert.Eventually(t, func() bool { return peer.failedPings.Load() == i }, 5*time.Second, time.Millisecond)
+		assert.True(t, b.hasConnectedTo(a.listenAddr))
+	}
+	clock.Advance(pingInterval)
+	assert.Eventually(t, func() bool { return !b.hasConnectedTo(a.listenAddr) }, 5*time.Second, time.Millisecond)
+}
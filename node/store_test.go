@@ -0,0 +1,50 @@
+package node
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBlockStorer(t *testing.T) {
+	s := NewMemoryBlockStorer()
+	block := utils.GenerateBlock(t, 0)
+	hash := hex.EncodeToString(types.HashBlockSHA256(block))
+
+	_, err := s.Get(hash)
+	assert.Error(t, err)
+	assert.NoError(t, s.Put(block))
+	stored, err := s.Get(hash)
+	assert.NoError(t, err)
+	assert.Equal(t, block, stored)
+}
+
+func BenchmarkMemoryBlockStorerPut(b *testing.B) {
+	s := NewMemoryBlockStorer()
+	blocks := utils.GenerateBlocks(b, b.N)
+	b.ResetTimer()
+	for _, block := range blocks {
+		if err := s.Put(block); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMemoryBlockStorerGet(b *testing.B) {
+	s := NewMemoryBlockStorer()
+	blocks := utils.GenerateBlocks(b, 1000)
+	hashes := make([]string, len(blocks))
+	for i, block := range blocks {
+		s.Put(block)
+		hashes[i] = hex.EncodeToString(types.HashBlockSHA256(block))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Get(hashes[i%len(hashes)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package node
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const subscriptionBufferSize = 256
+
+// stream forwards the events of the given kinds to send until the client goes away. A client too slow to keep
+// up is disconnected rather than silently missing events.
+func (q *queryServer) stream(ctx context.Context, send func(Event) error, kinds ...EventKind) error {
+	if q.events == nil {
+		return status.Error(codes.Unavailable, "subscriptions are not available")
+	}
+	sub := q.events.Subscribe(subscriptionBufferSize, kinds...)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-sub.C:
+			if sub.Dropped() > 0 {
+				return status.Error(codes.ResourceExhausted, "subscriber fell behind and missed events")
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (q *queryServer) SubscribeTips(_ *proto.Ack, stream proto.Query_SubscribeTipsServer) error {
+	return q.stream(stream.Context(), func(event Event) error {
+		return stream.Send(&proto.TipEvent{
+			Height: event.Height,
+			Hash:   types.HashBlockSHA256(event.Block),
+			Block:  event.Block,
+		})
+	}, EventNewTip)
+}
+
+func (q *queryServer) SubscribeReorgs(_ *proto.Ack, stream proto.Query_SubscribeReorgsServer) error {
+	return q.stream(stream.Context(), func(event Event) error {
+		return stream.Send(&proto.ReorgEvent{
+			Disconnected: event.Disconnected,
+			Connected:    event.Connected,
+			Height:       event.Height,
+		})
+	}, EventReorg)
+}
+
+func (q *queryServer) SubscribeMempool(_ *proto.Ack, stream proto.Query_SubscribeMempoolServer) error {
+	return q.stream(stream.Context(), func(event Event) error {
+		msg := &proto.MempoolEvent{
+			Type:        proto.MempoolEvent_ACCEPT,
+			TxHash:      types.HashTransactionSHA256(event.Transaction),
+			Transaction: event.Transaction,
+			Reason:      event.Reason,
+		}
+		if event.Kind == EventMempoolEvict {
+			msg.Type = proto.MempoolEvent_EVICT
+		}
+		return stream.Send(msg)
+	}, EventMempoolAccept, EventMempoolEvict)
+}
+
+// SubscribePayments reports the outputs paying any of the watched addresses, first when their transaction
+// is accepted in the mempool and then when it is confirmed on the main chain.
+func (q *queryServer) SubscribePayments(req *proto.AddressList, stream proto.Query_SubscribePaymentsServer) error {
+	if len(req.Addresses) == 0 {
+		return status.Error(codes.InvalidArgument, "no address to watch")
+	}
+	watched := map[string]bool{}
+	for _, address := range req.Addresses {
+		if _, err := crypto.AddressFromBytes(address); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		watched[hex.EncodeToString(address)] = true
+	}
+
+	sendPayments := func(tx *proto.Transaction, confirmed bool, height int32) error {
+		var txHash []byte
+		for i, output := range tx.Outputs {
+			if !watched[hex.EncodeToString(output.DestAddress)] {
+				continue
+			}
+			if txHash == nil {
+				txHash = types.HashTransactionSHA256(tx)
+			}
+			err := stream.Send(&proto.PaymentEvent{
+				Address:     output.DestAddress,
+				TxHash:      txHash,
+				OutputIndex: int32(i),
+				Amount:      output.Amount,
+				Confirmed:   confirmed,
+				Height:      height,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	sendBlock := func(block *proto.Block, height int32) error {
+		for _, tx := range block.Transaction {
+			if err := sendPayments(tx, true, height); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return q.stream(stream.Context(), func(event Event) error {
+		switch event.Kind {
+		case EventMempoolAccept:
+			return sendPayments(event.Transaction, false, 0)
+		case EventNewTip:
+			return sendBlock(event.Block, event.Height)
+		case EventReorg:
+			// The last connected block is the new tip, reported by the EventNewTip following the reorg.
+			first := event.Height - int32(len(event.Connected)) + 1
+			for i, block := range event.Connected[:len(event.Connected)-1] {
+				if err := sendBlock(block, first+int32(i)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}, EventMempoolAccept, EventNewTip, EventReorg)
+}
+
+func (q *queryServer) SubscribePeers(_ *proto.Ack, stream proto.Query_SubscribePeersServer) error {
+	return q.stream(stream.Context(), func(event Event) error {
+		msg := &proto.PeerEvent{Type: proto.PeerEvent_CONNECTED, Peer: event.Peer}
+		if event.Kind == EventPeerDisconnected {
+			msg.Type = proto.PeerEvent_DISCONNECTED
+		}
+		return stream.Send(msg)
+	}, EventPeerConnected, EventPeerDisconnected)
+}
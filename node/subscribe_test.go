@@ -0,0 +1,162 @@
+package node
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func startQueryServer(t *testing.T, q *queryServer) proto.QueryClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	proto.RegisterQueryServer(server, q)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewQueryClient(conn)
+}
+
+// waitForSubscribers lets a stream register its subscription before events are published.
+func waitForSubscribers(t *testing.T, bus *EventBus, count int) {
+	assert.Eventually(t, func() bool {
+		bus.mu.RLock()
+		defer bus.mu.RUnlock()
+		return len(bus.subs) >= count
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSubscribeTipsAndReorgs(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	c.events = NewEventBus()
+	client := startQueryServer(t, &queryServer{chain: c, events: c.events})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tips, err := client.SubscribeTips(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+	reorgs, err := client.SubscribeReorgs(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+	waitForSubscribers(t, c.events, 2)
+
+	genesis, _ := c.GetBlockByHeight(0)
+	a1 := utils.ChildBlock(t, genesis, utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 1))
+	assert.NoError(t, c.AddBlock(a1))
+	tip, err := tips.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), tip.Height)
+	assert.Equal(t, types.HashBlockSHA256(a1), tip.Hash)
+
+	b1 := utils.ChildBlock(t, genesis)
+	assert.NoError(t, c.AddBlock(b1))
+	b2 := utils.ChildBlock(t, b1)
+	assert.NoError(t, c.AddBlock(b2))
+	reorg, err := reorgs.Recv()
+	assert.NoError(t, err)
+	assert.Len(t, reorg.Disconnected, 1)
+	assert.Len(t, reorg.Connected, 2)
+	assert.Equal(t, int32(2), reorg.Height)
+	tip, err = tips.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, types.HashBlockSHA256(b2), tip.Hash)
+}
+
+func TestSubscribeMempoolAndPayments(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	c.events = NewEventBus()
+	m := NewMempool(10, c.events)
+	client := startQueryServer(t, &queryServer{chain: c, events: c.events})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	alice := crypto.GeneratePrivateKey()
+	bob := crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 100)
+	addTestBlock(t, c, mint)
+
+	mempool, err := client.SubscribeMempool(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+	payments, err := client.SubscribePayments(ctx, &proto.AddressList{Addresses: [][]byte{bob.Public().Address().Bytes()}})
+	assert.NoError(t, err)
+	waitForSubscribers(t, c.events, 2)
+
+	tx := utils.SpendTransaction(alice, mint, 0,
+		&proto.TxOutput{Amount: 40, DestAddress: bob.Public().Address().Bytes()},
+		&proto.TxOutput{Amount: 60, DestAddress: alice.Public().Address().Bytes()},
+	)
+	_, err = m.Add(tx)
+	assert.NoError(t, err)
+	accepted, err := mempool.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, proto.MempoolEvent_ACCEPT, accepted.Type)
+	assert.Equal(t, types.HashTransactionSHA256(tx), accepted.TxHash)
+
+	payment, err := payments.Recv()
+	assert.NoError(t, err)
+	assert.False(t, payment.Confirmed)
+	assert.Equal(t, int64(40), payment.Amount)
+	assert.Equal(t, int32(0), payment.OutputIndex)
+
+	block := addTestBlock(t, c, tx)
+	m.removeBlock(block)
+	evicted, err := mempool.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, proto.MempoolEvent_EVICT, evicted.Type)
+	assert.Equal(t, EvictIncluded, evicted.Reason)
+
+	payment, err = payments.Recv()
+	assert.NoError(t, err)
+	assert.True(t, payment.Confirmed)
+	assert.Equal(t, int32(2), payment.Height)
+}
+
+func TestSubscribePaymentsRequiresAddresses(t *testing.T) {
+	bus := NewEventBus()
+	client := startQueryServer(t, &queryServer{chain: NewChain(NewMemoryBlockStorer()), events: bus})
+
+	for _, req := range []*proto.AddressList{{}, {Addresses: [][]byte{{1, 2}}}} {
+		stream, err := client.SubscribePayments(context.Background(), req)
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
+
+func TestSubscribePeers(t *testing.T) {
+	bus := NewEventBus()
+	client := startQueryServer(t, &queryServer{chain: NewChain(NewMemoryBlockStorer()), events: bus})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	peers, err := client.SubscribePeers(ctx, &proto.Ack{})
+	assert.NoError(t, err)
+	waitForSubscribers(t, bus, 1)
+
+	bus.Publish(Event{Kind: EventPeerConnected, Peer: &proto.PeerInfo{Identity: "a"}})
+	bus.Publish(Event{Kind: EventPeerDisconnected, Peer: &proto.PeerInfo{Identity: "a"}})
+	event, err := peers.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, proto.PeerEvent_CONNECTED, event.Type)
+	assert.Equal(t, "a", event.Peer.Identity)
+	event, err = peers.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, proto.PeerEvent_DISCONNECTED, event.Type)
+}
@@ -0,0 +1,167 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxBlocksPerRequest = 500
+
+func (n *Node) GetBlocks(blockRange *proto.BlockRange, stream proto.Node_GetBlocksServer) error {
+	if !n.config.Services.Has(ServiceFullNode) {
+		return status.Error(codes.Unimplemented, "blocks are only served by full nodes")
+	}
+	if blockRange.FromHeight < 0 || blockRange.ToHeight < blockRange.FromHeight {
+		return status.Errorf(codes.InvalidArgument, "invalid block range %d-%d", blockRange.FromHeight, blockRange.ToHeight)
+	}
+
+	to := min(blockRange.ToHeight, n.chain.Height(), blockRange.FromHeight+maxBlocksPerRequest-1)
+	for height := blockRange.FromHeight; height <= to; height++ {
+		block, err := n.chain.GetBlockByHeight(height)
+		if err != nil {
+			return status.Error(codes.NotFound, err.Error())
+		}
+		if err := stream.Send(block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// requestSync asks the sync loop to catch up with the best peer; requests made while a sync is running are merged.
+func (n *Node) requestSync() {
+	select {
+	case n.syncCh <- struct{}{}:
+	default:
+	}
+}
+
+func (n *Node) syncLoop() {
+	for range n.syncCh {
+		n.syncing.Store(true)
+		n.syncWithPeers()
+		n.syncing.Store(false)
+	}
+}
+
+func (n *Node) bestPeer() *addPeerData {
+	var best *addPeerData
+	for _, peer := range n.peersWithService(ServiceFullNode) {
+		if best == nil || peer.data.Height > best.data.Height {
+			best = peer
+		}
+	}
+	return best
+}
+
+func (n *Node) syncWithPeers() {
+	for {
+		best := n.bestPeer()
+		height := n.chain.Height()
+		if best == nil || best.data.Height <= height {
+			return
+		}
+
+		peers := []*addPeerData{}
+		for _, peer := range n.peersWithService(ServiceFullNode) {
+			if peer.data.Height > height {
+				peers = append(peers, peer)
+			}
+		}
+		logger := n.chainLogger.WithFields(logrus.Fields{
+			"peers":      len(peers),
+			"height":     height,
+			"peerHeight": best.data.Height,
+		})
+		logger.Info("Syncing blocks")
+		if err := n.newBlockDownload(peers, height+1, best.data.Height).run(); err != nil {
+			logger.Warnf("Sync failed: %v", err)
+			return
+		}
+		if n.chain.Height() == height {
+			logger.Warn("Sync made no progress")
+			return
+		}
+	}
+}
+
+func (n *Node) downloadBlocks(peer *addPeerData, from int32, to int32) error {
+	stream, err := (*peer.client).GetBlocks(context.Background(), &proto.BlockRange{FromHeight: from, ToHeight: to})
+	if err != nil {
+		return err
+	}
+
+	for {
+		block, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := n.processBlock(peer, block); err != nil {
+			return err
+		}
+	}
+}
+
+// processBlock adds block, received from peer, to the chain. A block whose parent is unknown waits in the
+// orphan pool while its parents are requested from peer, and joins the chain along with them.
+func (n *Node) processBlock(peer *addPeerData, block *proto.Block) error {
+	err := n.addBlock(block)
+	if !errors.Is(err, ErrUnknownParent) {
+		return err
+	}
+
+	if err := n.orphans.add(block, peer.id()); err != nil {
+		return err
+	}
+	// the blocks of a branch arrive in order: only the first one needs to ask for its parents
+	if n.orphans.has(block.Header.PreviousHash) {
+		return nil
+	}
+	height := block.Header.Height
+	n.chainLogger.WithFields(logrus.Fields{
+		"peer":   peer.data.Address,
+		"height": height,
+	}).Info("Requesting the parents of an orphan block")
+	return n.downloadBlocks(peer, max(0, height-orphanParentWindow), height-1)
+}
+
+// addBlock adds block to the chain, then the orphans waiting for it.
+func (n *Node) addBlock(block *proto.Block) error {
+	start := time.Now()
+	if err := n.chain.AddBlock(block); err != nil {
+		if !errors.Is(err, ErrUnknownParent) {
+			n.metrics.invalid(err)
+		}
+		return err
+	}
+	n.metrics.observeBlockConnect(start)
+
+	parents := []*proto.Block{block}
+	for len(parents) > 0 {
+		parent := parents[0]
+		parents = parents[1:]
+		for _, orphan := range n.orphans.takeChildren(types.HashBlockSHA256(parent)) {
+			start := time.Now()
+			if err := n.chain.AddBlock(orphan); err != nil {
+				n.metrics.invalid(err)
+				n.chainLogger.Warnf("Dropped orphan block: %v", err)
+				continue
+			}
+			n.metrics.observeBlockConnect(start)
+			parents = append(parents, orphan)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package node
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	defaultCertFile = "tls.crt"
+	defaultKeyFile  = "tls.key"
+
+	selfSignedValidity = 10 * 365 * 24 * time.Hour
+)
+
+type TLSConfig struct {
+	Enabled bool
+	// CertFile, KeyFile and CAFile are resolved against the data directory when relative.
+	CertFile string
+	KeyFile  string
+	// CAFile optionally holds the PEM certificates of the authorities that sign peer certificates.
+	CAFile string
+	// AutoGenerate creates a self-signed certificate when CertFile does not exist yet.
+	AutoGenerate bool
+	// PinnedPeers holds the hex SHA-256 fingerprints of the certificates of the peers we accept.
+	PinnedPeers []string
+}
+
+type transportCredentials struct {
+	server      credentials.TransportCredentials
+	client      credentials.TransportCredentials
+	fingerprint string
+}
+
+func insecureCredentials() *transportCredentials {
+	return &transportCredentials{
+		server: insecure.NewCredentials(),
+		client: insecure.NewCredentials(),
+	}
+}
+
+func loadTransportCredentials(c *TLSConfig, dataDir string, listenAddr string) (*transportCredentials, error) {
+	if c == nil || !c.Enabled {
+		return insecureCredentials(), nil
+	}
+
+	certFile := resolvePath(dataDir, c.CertFile, defaultCertFile)
+	keyFile := resolvePath(dataDir, c.KeyFile, defaultKeyFile)
+	if _, err := os.Stat(certFile); os.IsNotExist(err) && c.AutoGenerate {
+		if err := generateSelfSignedCertificate(certFile, keyFile, listenAddr); err != nil {
+			return nil, fmt.Errorf("failed to generate certificate: %w", err)
+		}
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	verifier := &peerVerifier{pins: map[string]bool{}}
+	for _, pin := range c.PinnedPeers {
+		verifier.pins[strings.ToLower(pin)] = true
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(resolvePath(dataDir, c.CAFile, ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		verifier.roots = x509.NewCertPool()
+		if !verifier.roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+		}
+	}
+
+	serverConfig := &tls.Config{
+		Certificates:          []tls.Certificate{cert},
+		ClientAuth:            tls.RequireAnyClientCert,
+		VerifyPeerCertificate: verifier.verify,
+		MinVersion:            tls.VersionTLS13,
+	}
+	// Peers are addressed by host:port and often use self-signed certificates, so the standard hostname
+	// verification is replaced by peerVerifier, which checks the pins and the authorities instead.
+	clientConfig := &tls.Config{
+		Certificates:          []tls.Certificate{cert},
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verifier.verify,
+		MinVersion:            tls.VersionTLS13,
+	}
+
+	return &transportCredentials{
+		server:      credentials.NewTLS(serverConfig),
+		client:      credentials.NewTLS(clientConfig),
+		fingerprint: CertificateFingerprint(cert.Certificate[0]),
+	}, nil
+}
+
+// CertificateFingerprint returns the hex SHA-256 of a DER certificate, the format used by TLSConfig.PinnedPeers.
+func CertificateFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+type peerVerifier struct {
+	pins  map[string]bool
+	roots *x509.CertPool
+}
+
+// verify accepts a peer whose certificate is pinned or signed by a trusted authority. When neither pins nor
+// authorities are configured any certificate is accepted, and TLS only provides encryption.
+func (v *peerVerifier) verify(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("peer did not present a certificate")
+	}
+	if len(v.pins) == 0 && v.roots == nil {
+		return nil
+	}
+
+	fingerprint := CertificateFingerprint(rawCerts[0])
+	if v.pins[fingerprint] {
+		return nil
+	}
+
+	if v.roots != nil {
+		certs := make([]*x509.Certificate, 0, len(rawCerts))
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("invalid peer certificate: %w", err)
+			}
+			certs = append(certs, cert)
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         v.roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err == nil {
+			return nil
+		}
+		return fmt.Errorf("untrusted peer certificate %s: %w", fingerprint, err)
+	}
+
+	return fmt.Errorf("peer certificate %s is not pinned", fingerprint)
+}
+
+func generateSelfSignedCertificate(certFile string, keyFile string, listenAddr string) error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: listenAddr},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(selfSignedValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if host, _, err := net.SplitHostPort(listenAddr); err == nil && host != "" {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = []net.IP{ip}
+		} else {
+			template.DNSNames = []string{host}
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		return err
+	}
+	key, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(path.Dir(certFile), 0o755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(keyFile), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+func resolvePath(dataDir string, file string, fallback string) string {
+	if file == "" {
+		file = fallback
+	}
+	if path.IsAbs(file) {
+		return file
+	}
+	return path.Join(dataDir, file)
+}
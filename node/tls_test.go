@@ -0,0 +1,107 @@
+package node
+
+import (
+	"context"
+	"net"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/credentials"
+)
+
+func makeTestCredentials(t *testing.T, pins ...string) *transportCredentials {
+	c, err := loadTransportCredentials(&TLSConfig{Enabled: true, AutoGenerate: true, PinnedPeers: pins}, t.TempDir(), "localhost:3000")
+	assert.NoError(t, err)
+	return c
+}
+
+func tlsHandshake(t *testing.T, server *transportCredentials, client *transportCredentials) (credentials.AuthInfo, error) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		_, _, err := server.server.ServerHandshake(serverConn)
+		serverErr <- err
+		serverConn.Close()
+	}()
+	_, info, err := client.client.ClientHandshake(context.Background(), "localhost:3000", clientConn)
+	clientConn.Close()
+	if sErr := <-serverErr; err == nil {
+		err = sErr
+	}
+	return info, err
+}
+
+func TestTLSDisabledIsInsecure(t *testing.T) {
+	c, err := loadTransportCredentials(nil, t.TempDir(), "localhost:3000")
+	assert.NoError(t, err)
+	assert.Equal(t, "insecure", c.client.Info().SecurityProtocol)
+	assert.Empty(t, c.fingerprint)
+}
+
+func TestTLSAutoGenerateIsPersistent(t *testing.T) {
+	dir := t.TempDir()
+	config := &TLSConfig{Enabled: true, AutoGenerate: true}
+	first, err := loadTransportCredentials(config, dir, "localhost:3000")
+	assert.NoError(t, err)
+	assert.FileExists(t, path.Join(dir, defaultCertFile))
+	assert.FileExists(t, path.Join(dir, defaultKeyFile))
+
+	second, err := loadTransportCredentials(config, dir, "localhost:3000")
+	assert.NoError(t, err)
+	assert.Equal(t, first.fingerprint, second.fingerprint)
+}
+
+func TestTLSMissingCertificate(t *testing.T) {
+	_, err := loadTransportCredentials(&TLSConfig{Enabled: true}, t.TempDir(), "localhost:3000")
+	assert.Error(t, err)
+}
+
+func TestTLSMutualAuthentication(t *testing.T) {
+	a := makeTestCredentials(t)
+	b := makeTestCredentials(t)
+
+	info, err := tlsHandshake(t, a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, "tls", info.AuthType())
+}
+
+func TestTLSPinnedPeers(t *testing.T) {
+	a := makeTestCredentials(t)
+	b := makeTestCredentials(t)
+	pinnedA := makeTestCredentials(t, b.fingerprint)
+	pinnedB := makeTestCredentials(t, a.fingerprint)
+
+	// pinnedA only trusts b, which is not the certificate pinnedB uses
+	_, err := tlsHandshake(t, pinnedA, pinnedB)
+	assert.Error(t, err)
+
+	_, err = tlsHandshake(t, pinnedB, a)
+	assert.NoError(t, err)
+	_, err = tlsHandshake(t, a, pinnedB)
+	assert.NoError(t, err)
+}
+
+func TestTLSCertificateAuthority(t *testing.T) {
+	stranger := makeTestCredentials(t)
+	dir := t.TempDir()
+	config := &TLSConfig{Enabled: true, AutoGenerate: true, CAFile: "ca.crt"}
+	// a self-signed certificate acts as its own authority
+	self, err := loadTransportCredentials(&TLSConfig{Enabled: true, AutoGenerate: true}, dir, "localhost:3000")
+	assert.NoError(t, err)
+	pem, err := os.ReadFile(path.Join(dir, defaultCertFile))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path.Join(dir, "ca.crt"), pem, 0o644))
+
+	trusting, err := loadTransportCredentials(config, dir, "localhost:3000")
+	assert.NoError(t, err)
+
+	_, err = tlsHandshake(t, trusting, self)
+	assert.NoError(t, err)
+	_, err = tlsHandshake(t, trusting, stranger)
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const memoryTransportBufferSize = 1 << 20
+
+// Transport carries the connections between peers. Nodes use TCP unless Config.Transport says otherwise.
+type Transport interface {
+	Listen(address string) (net.Listener, error)
+	Dial(ctx context.Context, address string) (net.Conn, error)
+}
+
+type tcpTransport struct{}
+
+func (tcpTransport) Listen(address string) (net.Listener, error) {
+	return net.Listen("tcp", address)
+}
+
+func (tcpTransport) Dial(ctx context.Context, address string) (net.Conn, error) {
+	dialer := net.Dialer{}
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// MemoryTransport connects the nodes sharing it through in-memory pipes, without using the network. Addresses
+// are only names: any string works as long as no other node listens on it.
+type MemoryTransport struct {
+	mu        sync.Mutex
+	listeners map[string]*bufconn.Listener
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{listeners: map[string]*bufconn.Listener{}}
+}
+
+func (t *MemoryTransport) Listen(address string) (net.Listener, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.listeners[address]; ok {
+		return nil, fmt.Errorf("address %s already in use", address)
+	}
+	listener := bufconn.Listen(memoryTransportBufferSize)
+	t.listeners[address] = listener
+	return &memoryListener{Listener: listener, close: func() { t.remove(address, listener) }}, nil
+}
+
+func (t *MemoryTransport) Dial(ctx context.Context, address string) (net.Conn, error) {
+	t.mu.Lock()
+	listener, ok := t.listeners[address]
+	t.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("dial %s: connection refused", address)
+	}
+	return listener.DialContext(ctx)
+}
+
+func (t *MemoryTransport) remove(address string, listener *bufconn.Listener) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.listeners[address] == listener {
+		delete(t.listeners, address)
+	}
+}
+
+// memoryListener frees its address when closed.
+type memoryListener struct {
+	*bufconn.Listener
+	close func()
+}
+
+func (l *memoryListener) Close() error {
+	l.close()
+	return l.Listener.Close()
+}
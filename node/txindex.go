@@ -0,0 +1,76 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sync"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+)
+
+type txLocation struct {
+	blockHash []byte
+	index     int32
+}
+
+// TxIndex maps the hash of every transaction of the main chain to the block holding it.
+type TxIndex struct {
+	mu        sync.RWMutex
+	locations map[string]txLocation
+	height    int32
+}
+
+func NewTxIndex() *TxIndex {
+	return &TxIndex{locations: map[string]txLocation{}, height: -1}
+}
+
+func (ix *TxIndex) ConnectBlock(block *proto.Block, height int32, _ []*UTXO) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	blockHash := types.HashBlockSHA256(block)
+	for i, tx := range block.Transaction {
+		ix.locations[hex.EncodeToString(types.HashTransactionSHA256(tx))] = txLocation{blockHash: blockHash, index: int32(i)}
+	}
+	ix.height = height
+	return nil
+}
+
+func (ix *TxIndex) DisconnectBlock(block *proto.Block, height int32, _ []*UTXO) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	blockHash := types.HashBlockSHA256(block)
+	for _, tx := range block.Transaction {
+		key := hex.EncodeToString(types.HashTransactionSHA256(tx))
+		if location, ok := ix.locations[key]; ok && bytes.Equal(location.blockHash, blockHash) {
+			delete(ix.locations, key)
+		}
+	}
+	ix.height = height - 1
+	return nil
+}
+
+func (ix *TxIndex) Height() int32 {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return ix.height
+}
+
+func (ix *TxIndex) Reset() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.locations = map[string]txLocation{}
+	ix.height = -1
+}
+
+func (ix *TxIndex) Lookup(txHash []byte) (blockHash []byte, index int32, ok bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	location, ok := ix.locations[hex.EncodeToString(txHash)]
+	return location.blockHash, location.index, ok
+}
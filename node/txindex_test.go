@@ -0,0 +1,68 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxIndexLookup(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	assert.NoError(t, c.EnableTxIndex())
+	tx := utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 10)
+	block := addTestBlock(t, c, utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 5), tx)
+
+	blockHash, index, ok := c.txIndex.Lookup(types.HashTransactionSHA256(tx))
+	assert.True(t, ok)
+	assert.Equal(t, types.HashBlockSHA256(block), blockHash)
+	assert.Equal(t, int32(1), index)
+	assert.Equal(t, int32(1), c.txIndex.Height())
+
+	_, _, ok = c.txIndex.Lookup(utils.RandomHash(t))
+	assert.False(t, ok)
+}
+
+func TestTxIndexRebuildsOnExistingChain(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	tx := utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 10)
+	addTestBlock(t, c, tx)
+	addTestBlocks(t, c, 3)
+
+	assert.NoError(t, c.EnableTxIndex())
+	assert.Equal(t, c.Height(), c.txIndex.Height())
+	info, err := c.findTransaction(types.HashTransactionSHA256(tx))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), info.BlockHeight)
+	assert.Equal(t, int32(4), info.Confirmations)
+}
+
+func TestTxIndexFollowsReorgs(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	assert.NoError(t, c.EnableTxIndex())
+	genesis, err := c.GetBlockByHeight(0)
+	assert.NoError(t, err)
+
+	mainTx := utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 10)
+	main1 := utils.ChildBlock(t, genesis, mainTx)
+	assert.NoError(t, c.AddBlock(main1))
+
+	sideTx := utils.MintTransaction(crypto.GeneratePrivateKey().Public().Address(), 20)
+	side1 := utils.ChildBlock(t, genesis, sideTx)
+	assert.NoError(t, c.AddBlock(side1))
+	_, _, ok := c.txIndex.Lookup(types.HashTransactionSHA256(sideTx))
+	assert.False(t, ok)
+
+	side2 := utils.ChildBlock(t, side1)
+	assert.NoError(t, c.AddBlock(side2))
+	assert.Equal(t, int32(2), c.Height())
+
+	_, _, ok = c.txIndex.Lookup(types.HashTransactionSHA256(mainTx))
+	assert.False(t, ok)
+	blockHash, _, ok := c.txIndex.Lookup(types.HashTransactionSHA256(sideTx))
+	assert.True(t, ok)
+	assert.Equal(t, types.HashBlockSHA256(side1), blockHash)
+	assert.Equal(t, int32(2), c.txIndex.Height())
+}
@@ -0,0 +1,132 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+)
+
+type UTXO struct {
+	TxHash      []byte
+	OutputIndex int32
+	Output      *proto.TxOutput
+	Height      int32
+}
+
+func outpointKey(txHash []byte, index int32) string {
+	return fmt.Sprintf("%s:%d", hex.EncodeToString(txHash), index)
+}
+
+// UTXOSet holds the outputs of the main chain that have not been spent yet.
+type UTXOSet struct {
+	mu    sync.RWMutex
+	utxos map[string]*UTXO
+}
+
+func NewUTXOSet() *UTXOSet {
+	return &UTXOSet{utxos: map[string]*UTXO{}}
+}
+
+func (s *UTXOSet) Get(txHash []byte, index int32) (*UTXO, bool) {
+	return s.get(outpointKey(txHash, index))
+}
+
+func (s *UTXOSet) get(key string) (*UTXO, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	utxo, ok := s.utxos[key]
+	return utxo, ok
+}
+
+// ByAddress returns the unspent outputs paying address, oldest first.
+func (s *UTXOSet) ByAddress(address []byte) []*UTXO {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	utxos := []*UTXO{}
+	for _, utxo := range s.utxos {
+		if bytes.Equal(utxo.Output.DestAddress, address) {
+			utxos = append(utxos, utxo)
+		}
+	}
+	sort.Slice(utxos, func(i, j int) bool {
+		if utxos[i].Height != utxos[j].Height {
+			return utxos[i].Height < utxos[j].Height
+		}
+		if c := bytes.Compare(utxos[i].TxHash, utxos[j].TxHash); c != 0 {
+			return c < 0
+		}
+		return utxos[i].OutputIndex < utxos[j].OutputIndex
+	})
+	return utxos
+}
+
+func (s *UTXOSet) Balance(address []byte) int64 {
+	balance := int64(0)
+	for _, utxo := range s.ByAddress(address) {
+		balance += utxo.Output.Amount
+	}
+	return balance
+}
+
+// connectBlock spends the outputs referenced by the inputs of block and adds its new outputs. It returns
+// the outputs it spent. When block breaks the rules of spendBlock, the set is left unchanged and a
+// ValidationError tells which transaction is invalid.
+func (s *UTXOSet) connectBlock(block *proto.Block, height int32) ([]*UTXO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	spends, err := spendBlock(block, height, func(key string) (*UTXO, bool) {
+		utxo, ok := s.utxos[key]
+		return utxo, ok
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the outputs created and spent within block are neither added nor part of the undo data
+	undo := []*UTXO{}
+	for _, key := range spends.order {
+		if utxo, ok := s.utxos[key]; ok {
+			undo = append(undo, utxo)
+			delete(s.utxos, key)
+		}
+	}
+	for key, utxo := range spends.created {
+		if !spends.spent[key] {
+			s.utxos[key] = utxo
+		}
+	}
+	return undo, nil
+}
+
+// disconnectBlock reverts connectBlock, given the outputs block spent.
+func (s *UTXOSet) disconnectBlock(block *proto.Block, spent []*UTXO) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tx := range block.Transaction {
+		hash := types.HashTransactionSHA256(tx)
+		for i := range tx.Outputs {
+			delete(s.utxos, outpointKey(hash, int32(i)))
+		}
+	}
+	for _, utxo := range spent {
+		s.utxos[outpointKey(utxo.TxHash, utxo.OutputIndex)] = utxo
+	}
+}
+
+func (u *UTXO) toProto() *proto.UTXO {
+	return &proto.UTXO{
+		TxHash:      u.TxHash,
+		OutputIndex: u.OutputIndex,
+		Output:      u.Output,
+		Height:      u.Height,
+	}
+}
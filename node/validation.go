@@ -0,0 +1,170 @@
+package node
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+	"math"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+)
+
+// Reasons a transaction or block fails validation, reported with the validation failure metric.
+const (
+	InvalidStructure    = "structure"
+	InvalidUnsigned     = "unsigned"
+	InvalidDoubleSpend  = "double_spend"
+	InvalidUnknownInput = "unknown_input"
+	InvalidOwner        = "owner"
+	InvalidOutput       = "output"
+	InvalidOverspend    = "overspend"
+	InvalidSignature    = "signature"
+	InvalidConflict     = "conflict"
+	InvalidMerkleRoot   = "merkle_root"
+	InvalidOverflow     = "overflow"
+)
+
+// ValidationError tells why a transaction or block is invalid. Reason is one of the Invalid constants.
+type ValidationError struct {
+	Reason string
+	err    error
+}
+
+func invalid(reason string, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{Reason: reason, err: fmt.Errorf(format, args...)}
+}
+
+func (e *ValidationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.err
+}
+
+// validateTransaction checks that tx only spends unspent outputs of utxos owned by the keys signing it,
+// and that it does not create more than it spends.
+func validateTransaction(tx *proto.Transaction, utxos *UTXOSet) error {
+	if len(tx.Inputs) == 0 {
+		return invalid(InvalidStructure, "transaction has no inputs")
+	}
+	if len(tx.Outputs) == 0 {
+		return invalid(InvalidStructure, "transaction has no outputs")
+	}
+
+	for i, input := range tx.Inputs {
+		if _, err := crypto.PublicKeyFromBytes(input.PublicKey); err != nil || len(input.Signature) != ed25519.SignatureSize {
+			return invalid(InvalidUnsigned, "input %d is not signed", i)
+		}
+	}
+	for i, output := range tx.Outputs {
+		if output.Amount <= 0 {
+			return invalid(InvalidOutput, "output %d has invalid amount %d", i, output.Amount)
+		}
+		if _, err := crypto.AddressFromBytes(output.DestAddress); err != nil {
+			return invalid(InvalidOutput, "output %d: %w", i, err)
+		}
+	}
+	if _, err := spendInputs(tx, utxos.get); err != nil {
+		return err
+	}
+
+	if !types.VerifyTransaction(tx) {
+		return invalid(InvalidSignature, "invalid signature")
+	}
+	return nil
+}
+
+// spendInputs checks that the inputs of tx spend distinct outputs found by utxo, owned by the keys of the
+// inputs and worth at least what tx pays. It returns the outputs spent. The signatures are not checked.
+func spendInputs(tx *proto.Transaction, utxo func(key string) (*UTXO, bool)) ([]*UTXO, error) {
+	spent := []*UTXO{}
+	seen := map[string]bool{}
+	in := int64(0)
+	for i, input := range tx.Inputs {
+		key := outpointKey(input.PreviousTxHash, input.PrevOutputIndex)
+		if seen[key] {
+			return nil, invalid(InvalidDoubleSpend, "input %d spends %s twice", i, key)
+		}
+		seen[key] = true
+
+		output, ok := utxo(key)
+		if !ok {
+			return nil, invalid(InvalidUnknownInput, "input %d spends unknown output %s", i, key)
+		}
+		publicKey, err := crypto.PublicKeyFromBytes(input.PublicKey)
+		if err != nil || !bytes.Equal(publicKey.Address().Bytes(), output.Output.DestAddress) {
+			return nil, invalid(InvalidOwner, "input %d is not signed by the owner of %s", i, key)
+		}
+		if in, ok = addAmount(in, output.Output.Amount); !ok {
+			return nil, invalid(InvalidOverflow, "input %d: invalid amount %d after %d", i, output.Output.Amount, in)
+		}
+		spent = append(spent, output)
+	}
+
+	out := int64(0)
+	for i, output := range tx.Outputs {
+		var ok bool
+		if out, ok = addAmount(out, output.Amount); !ok {
+			return nil, invalid(InvalidOverflow, "output %d: invalid amount %d after %d", i, output.Amount, out)
+		}
+	}
+	if out > in {
+		return nil, invalid(InvalidOverspend, "transaction spends %d but only has %d", out, in)
+	}
+	return spent, nil
+}
+
+// addAmount returns total plus amount, or false when amount is negative or the sum does not fit in an int64.
+func addAmount(total int64, amount int64) (int64, bool) {
+	if amount < 0 || total > math.MaxInt64-amount {
+		return total, false
+	}
+	return total + amount, true
+}
+
+// validateBlock checks what can be checked on block alone, before it is stored: a well formed header committing
+// to the transactions, and transactions paying valid outputs and signed by the keys of their inputs. Whether the
+// inputs are unspent is only known once the block is connected, see spendBlock. The signatures of the
+// transactions in verified are not checked again.
+func validateBlock(block *proto.Block, verified *sigCache) error {
+	if err := checkBlock(block); err != nil {
+		return err
+	}
+	return verifyBlockSignatures(block, verified)
+}
+
+// checkBlock is validateBlock without the signature checks.
+func checkBlock(block *proto.Block) error {
+	header := block.GetHeader()
+	if header == nil {
+		return invalid(InvalidStructure, "block has no header")
+	}
+	if len(header.PreviousHash) != sha256.Size {
+		return invalid(InvalidStructure, "invalid previous hash size %d", len(header.PreviousHash))
+	}
+	if len(header.MerkleRoot) != sha256.Size {
+		return invalid(InvalidStructure, "invalid merkle root size %d", len(header.MerkleRoot))
+	}
+	if err := checkMerkleRoot(block); err != nil {
+		return err
+	}
+
+	for i, tx := range block.Transaction {
+		if len(tx.Outputs) == 0 {
+			return invalid(InvalidStructure, "transaction %d has no outputs", i)
+		}
+		for j, output := range tx.Outputs {
+			if output.Amount <= 0 {
+				return invalid(InvalidOutput, "transaction %d: output %d has invalid amount %d", i, j, output.Amount)
+			}
+			if _, err := crypto.AddressFromBytes(output.DestAddress); err != nil {
+				return invalid(InvalidOutput, "transaction %d: output %d: %w", i, j, err)
+			}
+		}
+	}
+	return nil
+}
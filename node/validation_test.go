@@ -0,0 +1,153 @@
+package node
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
+	"github.com/stretchr/testify/assert"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// fuzzKey owns the output spent by the seed corpus of FuzzValidateTransaction.
+func fuzzKey() *crypto.PrivateKey {
+	return crypto.GeneratePrivateKeyFromSeed(make([]byte, 32))
+}
+
+func TestValidateBlock(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	genesis, _ := c.GetBlockByHeight(0)
+	alice := crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	pay := &proto.TxOutput{Amount: 10, DestAddress: alice.Public().Address().Bytes()}
+
+	assert.NoError(t, validateBlock(utils.ChildBlock(t, genesis, mint, utils.SpendTransaction(alice, mint, 0, pay)), c.verified))
+	assert.Error(t, validateBlock(&proto.Block{}, c.verified))
+
+	block := utils.ChildBlock(t, genesis)
+	block.Header.PreviousHash = block.Header.PreviousHash[:31]
+	assert.Error(t, validateBlock(block, c.verified))
+	assert.Error(t, c.AddBlock(block))
+
+	unsigned := utils.SpendTransaction(alice, mint, 0, pay)
+	unsigned.Inputs[0].Signature = nil
+	assert.Error(t, validateBlock(utils.ChildBlock(t, genesis, unsigned), c.verified))
+	assert.Error(t, validateBlock(utils.ChildBlock(t, genesis, utils.MintTransaction(alice.Public().Address(), 0)), c.verified))
+	assert.Error(t, validateBlock(utils.ChildBlock(t, genesis, &proto.Transaction{}), c.verified))
+}
+
+func TestOverflowingSpendIsRejected(t *testing.T) {
+	c := NewChain(NewMemoryBlockStorer())
+	genesis, _ := c.GetBlockByHeight(0)
+	alice, bob := crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()
+	mint := utils.MintTransaction(alice.Public().Address(), 10)
+	base := utils.ChildBlock(t, genesis, mint)
+	assert.NoError(t, c.AddBlock(base))
+
+	huge := &proto.TxOutput{Amount: math.MaxInt64, DestAddress: bob.Public().Address().Bytes()}
+	tx := utils.SpendTransaction(alice, mint, 0, huge, huge)
+	var validationErr *ValidationError
+	assert.ErrorAs(t, validateTransaction(tx, c.utxos), &validationErr)
+	assert.Equal(t, InvalidOverflow, validationErr.Reason)
+	assert.ErrorAs(t, c.AddBlock(utils.ChildBlock(t, base, tx)), &validationErr)
+	assert.Equal(t, InvalidOverflow, validationErr.Reason)
+	assert.Equal(t, int32(1), c.Height())
+	assert.Zero(t, c.utxos.Balance(bob.Public().Address().Bytes()))
+
+	// outputs minted at the limit cannot be spent together
+	big := utils.MintTransaction(alice.Public().Address(), math.MaxInt64)
+	assert.NoError(t, c.AddBlock(utils.ChildBlock(t, base, big)))
+	in := &proto.Transaction{Version: 1, Outputs: []*proto.TxOutput{{Amount: 1, DestAddress: bob.Public().Address().Bytes()}}}
+	for _, prev := range []*proto.Transaction{mint, big} {
+		in.Inputs = append(in.Inputs, &proto.TxInput{
+			PreviousTxHash: types.HashTransactionSHA256(prev),
+			PublicKey:      alice.Public().Bytes(),
+		})
+	}
+	for i := range in.Inputs {
+		in.Inputs[i].Signature = types.SignTransaction(in, alice).Bytes()
+	}
+	assert.ErrorAs(t, validateTransaction(in, c.utxos), &validationErr)
+	assert.Equal(t, InvalidOverflow, validationErr.Reason)
+}
+
+func FuzzValidateTransaction(f *testing.F) {
+	c := NewChain(NewMemoryBlockStorer())
+	genesis, _ := c.GetBlockByHeight(0)
+	mint := utils.MintTransaction(fuzzKey().Public().Address(), 100)
+	if err := c.AddBlock(utils.ChildBlock(f, genesis, mint)); err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tx := &proto.Transaction{}
+		if err := pb.Unmarshal(data, tx); err != nil {
+			return
+		}
+		err := validateTransaction(tx, c.utxos)
+		if err != nil {
+			var validationErr *ValidationError
+			assert.ErrorAs(t, err, &validationErr)
+		}
+	})
+}
+
+func FuzzValidateBlock(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		block := &proto.Block{}
+		if err := pb.Unmarshal(data, block); err != nil {
+			return
+		}
+		c := NewChain(NewMemoryBlockStorer())
+		if validateBlock(block, c.verified) != nil {
+			assert.Error(t, c.AddBlock(block))
+			return
+		}
+		c.AddBlock(block)
+	})
+}
+
+// benchmarkBlock returns a chain and a block extending it with count transactions, each spending an output
+// of the chain.
+func benchmarkBlock(b *testing.B, count int) (*Chain, *proto.Block) {
+	c := NewChain(NewMemoryBlockStorer())
+	key := fuzzKey()
+	mints := make([]*proto.Transaction, count)
+	for i := range mints {
+		mints[i] = utils.MintTransaction(key.Public().Address(), int64(i+1))
+	}
+	genesis, _ := c.GetBlockByHeight(0)
+	funding := utils.ChildBlock(b, genesis, mints...)
+	if err := c.AddBlock(funding); err != nil {
+		b.Fatal(err)
+	}
+
+	spends := make([]*proto.Transaction, count)
+	for i, mint := range mints {
+		spends[i] = utils.SpendTransaction(key, mint, 0, mint.Outputs[0])
+	}
+	block := utils.ChildBlock(b, funding, spends...)
+	return c, block
+}
+
+func BenchmarkValidateBlock(b *testing.B) {
+	for _, count := range []int{1, 10, 100, 1000} {
+		c, block := benchmarkBlock(b, count)
+		b.Run(fmt.Sprintf("transactions=%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := validateBlock(block, c.verified); err != nil {
+					b.Fatal(err)
+				}
+				for _, tx := range block.Transaction {
+					if err := validateTransaction(tx, c.utxos); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package node
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+)
+
+const (
+	// ProtocolVersion is the version of the peer protocol spoken by this node; MinProtocolVersion is the
+	// oldest version it can still talk to. Version 2.0.0 changed the hash signed by the inputs of a transaction
+	// (see types.HashTransactionForSigning) and added the block consensus rules of checkMerkleRoot and
+	// spendBlock: nodes of version 1 disagree on which transactions and blocks are valid.
+	ProtocolVersion    = "2.0.0"
+	MinProtocolVersion = "2.0.0"
+)
+
+type ServiceFlag uint64
+
+const (
+	ServiceFullNode ServiceFlag = 1 << iota
+	ServicePruned
+	ServiceLightServer
+	ServiceBlockProducer
+)
+
+var serviceNames = []struct {
+	flag ServiceFlag
+	name string
+}{
+	{ServiceFullNode, "full"},
+	{ServicePruned, "pruned"},
+	{ServiceLightServer, "light-server"},
+	{ServiceBlockProducer, "block-producer"},
+}
+
+func (f ServiceFlag) Has(other ServiceFlag) bool {
+	return f&other == other
+}
+
+func (f ServiceFlag) String() string {
+	names := []string{}
+	for _, s := range serviceNames {
+		if f.Has(s.flag) {
+			names = append(names, s.name)
+			f &^= s.flag
+		}
+	}
+	if f != 0 {
+		names = append(names, fmt.Sprintf("0x%x", uint64(f)))
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+type protocolVersion struct {
+	major, minor, patch int
+}
+
+func parseVersion(v string) (protocolVersion, error) {
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	if len(parts) != 3 {
+		return protocolVersion{}, fmt.Errorf("invalid version %q", v)
+	}
+
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return protocolVersion{}, fmt.Errorf("invalid version %q", v)
+		}
+		numbers[i] = n
+	}
+
+	return protocolVersion{major: numbers[0], minor: numbers[1], patch: numbers[2]}, nil
+}
+
+func (v protocolVersion) compare(other protocolVersion) int {
+	switch {
+	case v.major != other.major:
+		return v.major - other.major
+	case v.minor != other.minor:
+		return v.minor - other.minor
+	default:
+		return v.patch - other.patch
+	}
+}
+
+// checkCompatibility tells why a peer announcing msg cannot be talked to: its version must be at least our
+// minimum, ours must be at least its minimum, and it must offer the services we require.
+func checkCompatibility(msg *proto.HandshakeMsg, required ServiceFlag) error {
+	theirs, err := parseVersion(msg.Version)
+	if err != nil {
+		return fmt.Errorf("peer announced an invalid version: %w", err)
+	}
+	ours, _ := parseVersion(ProtocolVersion)
+	ourMin, _ := parseVersion(MinProtocolVersion)
+	if theirs.compare(ourMin) < 0 {
+		return fmt.Errorf("peer protocol version %s is older than the minimum supported %s", msg.Version, MinProtocolVersion)
+	}
+
+	if msg.MinVersion != "" {
+		theirMin, err := parseVersion(msg.MinVersion)
+		if err != nil {
+			return fmt.Errorf("peer announced an invalid minimum version: %w", err)
+		}
+		if ours.compare(theirMin) < 0 {
+			return fmt.Errorf("peer requires protocol version %s, we speak %s", msg.MinVersion, ProtocolVersion)
+		}
+	}
+
+	services := ServiceFlag(msg.Services)
+	if !services.Has(required) {
+		return fmt.Errorf("peer offers services %s, %s are required", services, required&^services)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package node
+
+import (
+	"testing"
+
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVersion(t *testing.T) {
+	v, err := parseVersion("1.2.3")
+	assert.NoError(t, err)
+	assert.Equal(t, protocolVersion{1, 2, 3}, v)
+
+	v, err = parseVersion("v2.0.10")
+	assert.NoError(t, err)
+	assert.Equal(t, protocolVersion{2, 0, 10}, v)
+
+	for _, invalid := range []string{"", "1", "1.2", "1.2.x", "1.-2.3", "1.2.3.4"} {
+		_, err := parseVersion(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	v := func(s string) protocolVersion {
+		parsed, err := parseVersion(s)
+		assert.NoError(t, err)
+		return parsed
+	}
+
+	assert.Zero(t, v("1.2.3").compare(v("1.2.3")))
+	assert.Negative(t, v("1.2.3").compare(v("1.2.4")))
+	assert.Negative(t, v("1.2.3").compare(v("1.10.0")))
+	assert.Positive(t, v("2.0.0").compare(v("1.99.99")))
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	compatible := &proto.HandshakeMsg{Version: ProtocolVersion, MinVersion: MinProtocolVersion, Services: uint64(ServiceFullNode)}
+	assert.NoError(t, checkCompatibility(compatible, ServiceFullNode))
+
+	tooOld := &proto.HandshakeMsg{Version: "1.0.0", Services: uint64(ServiceFullNode)}
+	assert.ErrorContains(t, checkCompatibility(tooOld, 0), "older than the minimum supported")
+
+	tooNew := &proto.HandshakeMsg{Version: "3.0.0", MinVersion: "3.0.0"}
+	assert.ErrorContains(t, checkCompatibility(tooNew, 0), "requires protocol version 3.0.0")
+
+	invalid := &proto.HandshakeMsg{Version: "latest"}
+	assert.ErrorContains(t, checkCompatibility(invalid, 0), "invalid version")
+
+	light := &proto.HandshakeMsg{Version: ProtocolVersion, Services: uint64(ServicePruned)}
+	assert.ErrorContains(t, checkCompatibility(light, ServiceFullNode|ServicePruned), "full are required")
+}
+
+func TestVersion1PeersAreRefused(t *testing.T) {
+	// version 1 nodes sign multi-input transactions differently (see types.HashTransactionForSigning)
+	for _, version := range []string{"1.0.0", "1.99.99"} {
+		peer := &proto.HandshakeMsg{Version: version, MinVersion: "1.0.0", Services: uint64(ServiceFullNode)}
+		assert.Error(t, checkCompatibility(peer, 0), version)
+	}
+}
+
+func TestServiceFlagString(t *testing.T) {
+	assert.Equal(t, "none", ServiceFlag(0).String())
+	assert.Equal(t, "full|block-producer", (ServiceFullNode | ServiceBlockProducer).String())
+	assert.Equal(t, "pruned|0x100", (ServicePruned | ServiceFlag(0x100)).String())
+}
+
+func FuzzCheckCompatibility(f *testing.F) {
+	f.Add("2.0.0", "2.0.0", uint64(ServiceFullNode))
+	f.Add("v2.1.3", "", uint64(0))
+	f.Add("1.2", "x.y.z", uint64(1<<63))
+
+	f.Fuzz(func(t *testing.T, version string, minVersion string, services uint64) {
+		msg := &proto.HandshakeMsg{Version: version, MinVersion: minVersion, Services: services}
+		if checkCompatibility(msg, ServiceFullNode) == nil {
+			_, err := parseVersion(version)
+			assert.NoError(t, err)
+		}
+		assert.NotEmpty(t, ServiceFlag(services).String())
+	})
+}
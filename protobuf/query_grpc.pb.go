@@ -0,0 +1,781 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v5.27.0
+// source: protobuf/query.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// QueryClient is the client API for Query service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type QueryClient interface {
+	GetTip(ctx context.Context, in *Ack, opts ...grpc.CallOption) (*ChainTip, error)
+	GetBlockByHash(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*Block, error)
+	GetBlockByHeight(ctx context.Context, in *HeightRequest, opts ...grpc.CallOption) (*Block, error)
+	ListBlocks(ctx context.Context, in *PageRequest, opts ...grpc.CallOption) (*BlockList, error)
+	GetTransaction(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*TransactionInfo, error)
+	GetUTXOs(ctx context.Context, in *AddressRequest, opts ...grpc.CallOption) (*UTXOList, error)
+	GetBalance(ctx context.Context, in *AddressRequest, opts ...grpc.CallOption) (*Balance, error)
+	GetAddressHistory(ctx context.Context, in *AddressRequest, opts ...grpc.CallOption) (*AddressHistory, error)
+	GetBalanceAtHeight(ctx context.Context, in *BalanceRequest, opts ...grpc.CallOption) (*Balance, error)
+	GetMempool(ctx context.Context, in *PageRequest, opts ...grpc.CallOption) (*MempoolList, error)
+	SendTransaction(ctx context.Context, in *Transaction, opts ...grpc.CallOption) (*TxReceipt, error)
+	SubscribeTips(ctx context.Context, in *Ack, opts ...grpc.CallOption) (Query_SubscribeTipsClient, error)
+	SubscribeReorgs(ctx context.Context, in *Ack, opts ...grpc.CallOption) (Query_SubscribeReorgsClient, error)
+	SubscribeMempool(ctx context.Context, in *Ack, opts ...grpc.CallOption) (Query_SubscribeMempoolClient, error)
+	SubscribePayments(ctx context.Context, in *AddressList, opts ...grpc.CallOption) (Query_SubscribePaymentsClient, error)
+	SubscribePeers(ctx context.Context, in *Ack, opts ...grpc.CallOption) (Query_SubscribePeersClient, error)
+}
+
+type queryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewQueryClient(cc grpc.ClientConnInterface) QueryClient {
+	return &queryClient{cc}
+}
+
+func (c *queryClient) GetTip(ctx context.Context, in *Ack, opts ...grpc.CallOption) (*ChainTip, error) {
+	out := new(ChainTip)
+	err := c.cc.Invoke(ctx, "/Query/GetTip", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetBlockByHash(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*Block, error) {
+	out := new(Block)
+	err := c.cc.Invoke(ctx, "/Query/GetBlockByHash", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetBlockByHeight(ctx context.Context, in *HeightRequest, opts ...grpc.CallOption) (*Block, error) {
+	out := new(Block)
+	err := c.cc.Invoke(ctx, "/Query/GetBlockByHeight", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) ListBlocks(ctx context.Context, in *PageRequest, opts ...grpc.CallOption) (*BlockList, error) {
+	out := new(BlockList)
+	err := c.cc.Invoke(ctx, "/Query/ListBlocks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetTransaction(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*TransactionInfo, error) {
+	out := new(TransactionInfo)
+	err := c.cc.Invoke(ctx, "/Query/GetTransaction", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetUTXOs(ctx context.Context, in *AddressRequest, opts ...grpc.CallOption) (*UTXOList, error) {
+	out := new(UTXOList)
+	err := c.cc.Invoke(ctx, "/Query/GetUTXOs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetBalance(ctx context.Context, in *AddressRequest, opts ...grpc.CallOption) (*Balance, error) {
+	out := new(Balance)
+	err := c.cc.Invoke(ctx, "/Query/GetBalance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetAddressHistory(ctx context.Context, in *AddressRequest, opts ...grpc.CallOption) (*AddressHistory, error) {
+	out := new(AddressHistory)
+	err := c.cc.Invoke(ctx, "/Query/GetAddressHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetBalanceAtHeight(ctx context.Context, in *BalanceRequest, opts ...grpc.CallOption) (*Balance, error) {
+	out := new(Balance)
+	err := c.cc.Invoke(ctx, "/Query/GetBalanceAtHeight", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) GetMempool(ctx context.Context, in *PageRequest, opts ...grpc.CallOption) (*MempoolList, error) {
+	out := new(MempoolList)
+	err := c.cc.Invoke(ctx, "/Query/GetMempool", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) SendTransaction(ctx context.Context, in *Transaction, opts ...grpc.CallOption) (*TxReceipt, error) {
+	out := new(TxReceipt)
+	err := c.cc.Invoke(ctx, "/Query/SendTransaction", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) SubscribeTips(ctx context.Context, in *Ack, opts ...grpc.CallOption) (Query_SubscribeTipsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Query_ServiceDesc.Streams[0], "/Query/SubscribeTips", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &querySubscribeTipsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Query_SubscribeTipsClient interface {
+	Recv() (*TipEvent, error)
+	grpc.ClientStream
+}
+
+type querySubscribeTipsClient struct {
+	grpc.ClientStream
+}
+
+func (x *querySubscribeTipsClient) Recv() (*TipEvent, error) {
+	m := new(TipEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *queryClient) SubscribeReorgs(ctx context.Context, in *Ack, opts ...grpc.CallOption) (Query_SubscribeReorgsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Query_ServiceDesc.Streams[1], "/Query/SubscribeReorgs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &querySubscribeReorgsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Query_SubscribeReorgsClient interface {
+	Recv() (*ReorgEvent, error)
+	grpc.ClientStream
+}
+
+type querySubscribeReorgsClient struct {
+	grpc.ClientStream
+}
+
+func (x *querySubscribeReorgsClient) Recv() (*ReorgEvent, error) {
+	m := new(ReorgEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *queryClient) SubscribeMempool(ctx context.Context, in *Ack, opts ...grpc.CallOption) (Query_SubscribeMempoolClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Query_ServiceDesc.Streams[2], "/Query/SubscribeMempool", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &querySubscribeMempoolClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Query_SubscribeMempoolClient interface {
+	Recv() (*MempoolEvent, error)
+	grpc.ClientStream
+}
+
+type querySubscribeMempoolClient struct {
+	grpc.ClientStream
+}
+
+func (x *querySubscribeMempoolClient) Recv() (*MempoolEvent, error) {
+	m := new(MempoolEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *queryClient) SubscribePayments(ctx context.Context, in *AddressList, opts ...grpc.CallOption) (Query_SubscribePaymentsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Query_ServiceDesc.Streams[3], "/Query/SubscribePayments", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &querySubscribePaymentsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Query_SubscribePaymentsClient interface {
+	Recv() (*PaymentEvent, error)
+	grpc.ClientStream
+}
+
+type querySubscribePaymentsClient struct {
+	grpc.ClientStream
+}
+
+func (x *querySubscribePaymentsClient) Recv() (*PaymentEvent, error) {
+	m := new(PaymentEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *queryClient) SubscribePeers(ctx context.Context, in *Ack, opts ...grpc.CallOption) (Query_SubscribePeersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Query_ServiceDesc.Streams[4], "/Query/SubscribePeers", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &querySubscribePeersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Query_SubscribePeersClient interface {
+	Recv() (*PeerEvent, error)
+	grpc.ClientStream
+}
+
+type querySubscribePeersClient struct {
+	grpc.ClientStream
+}
+
+func (x *querySubscribePeersClient) Recv() (*PeerEvent, error) {
+	m := new(PeerEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// QueryServer is the server API for Query service.
+// All implementations must embed UnimplementedQueryServer
+// for forward compatibility
+type QueryServer interface {
+	GetTip(context.Context, *Ack) (*ChainTip, error)
+	GetBlockByHash(context.Context, *HashRequest) (*Block, error)
+	GetBlockByHeight(context.Context, *HeightRequest) (*Block, error)
+	ListBlocks(context.Context, *PageRequest) (*BlockList, error)
+	GetTransaction(context.Context, *HashRequest) (*TransactionInfo, error)
+	GetUTXOs(context.Context, *AddressRequest) (*UTXOList, error)
+	GetBalance(context.Context, *AddressRequest) (*Balance, error)
+	GetAddressHistory(context.Context, *AddressRequest) (*AddressHistory, error)
+	GetBalanceAtHeight(context.Context, *BalanceRequest) (*Balance, error)
+	GetMempool(context.Context, *PageRequest) (*MempoolList, error)
+	SendTransaction(context.Context, *Transaction) (*TxReceipt, error)
+	SubscribeTips(*Ack, Query_SubscribeTipsServer) error
+	SubscribeReorgs(*Ack, Query_SubscribeReorgsServer) error
+	SubscribeMempool(*Ack, Query_SubscribeMempoolServer) error
+	SubscribePayments(*AddressList, Query_SubscribePaymentsServer) error
+	SubscribePeers(*Ack, Query_SubscribePeersServer) error
+	mustEmbedUnimplementedQueryServer()
+}
+
+// UnimplementedQueryServer must be embedded to have forward compatible implementations.
+type UnimplementedQueryServer struct {
+}
+
+func (UnimplementedQueryServer) GetTip(context.Context, *Ack) (*ChainTip, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTip not implemented")
+}
+func (UnimplementedQueryServer) GetBlockByHash(context.Context, *HashRequest) (*Block, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockByHash not implemented")
+}
+func (UnimplementedQueryServer) GetBlockByHeight(context.Context, *HeightRequest) (*Block, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockByHeight not implemented")
+}
+func (UnimplementedQueryServer) ListBlocks(context.Context, *PageRequest) (*BlockList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBlocks not implemented")
+}
+func (UnimplementedQueryServer) GetTransaction(context.Context, *HashRequest) (*TransactionInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransaction not implemented")
+}
+func (UnimplementedQueryServer) GetUTXOs(context.Context, *AddressRequest) (*UTXOList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUTXOs not implemented")
+}
+func (UnimplementedQueryServer) GetBalance(context.Context, *AddressRequest) (*Balance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedQueryServer) GetAddressHistory(context.Context, *AddressRequest) (*AddressHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAddressHistory not implemented")
+}
+func (UnimplementedQueryServer) GetBalanceAtHeight(context.Context, *BalanceRequest) (*Balance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalanceAtHeight not implemented")
+}
+func (UnimplementedQueryServer) GetMempool(context.Context, *PageRequest) (*MempoolList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMempool not implemented")
+}
+func (UnimplementedQueryServer) SendTransaction(context.Context, *Transaction) (*TxReceipt, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendTransaction not implemented")
+}
+func (UnimplementedQueryServer) SubscribeTips(*Ack, Query_SubscribeTipsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeTips not implemented")
+}
+func (UnimplementedQueryServer) SubscribeReorgs(*Ack, Query_SubscribeReorgsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeReorgs not implemented")
+}
+func (UnimplementedQueryServer) SubscribeMempool(*Ack, Query_SubscribeMempoolServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeMempool not implemented")
+}
+func (UnimplementedQueryServer) SubscribePayments(*AddressList, Query_SubscribePaymentsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribePayments not implemented")
+}
+func (UnimplementedQueryServer) SubscribePeers(*Ack, Query_SubscribePeersServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribePeers not implemented")
+}
+func (UnimplementedQueryServer) mustEmbedUnimplementedQueryServer() {}
+
+// UnsafeQueryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to QueryServer will
+// result in compilation errors.
+type UnsafeQueryServer interface {
+	mustEmbedUnimplementedQueryServer()
+}
+
+func RegisterQueryServer(s grpc.ServiceRegistrar, srv QueryServer) {
+	s.RegisterService(&Query_ServiceDesc, srv)
+}
+
+func _Query_GetTip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Ack)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetTip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetTip",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetTip(ctx, req.(*Ack))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetBlockByHash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetBlockByHash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetBlockByHash",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetBlockByHash(ctx, req.(*HashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetBlockByHeight_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeightRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetBlockByHeight(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetBlockByHeight",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetBlockByHeight(ctx, req.(*HeightRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_ListBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).ListBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/ListBlocks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).ListBlocks(ctx, req.(*PageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetTransaction(ctx, req.(*HashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetUTXOs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetUTXOs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetUTXOs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetUTXOs(ctx, req.(*AddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetBalance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetBalance(ctx, req.(*AddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetAddressHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetAddressHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetAddressHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetAddressHistory(ctx, req.(*AddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetBalanceAtHeight_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetBalanceAtHeight(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetBalanceAtHeight",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetBalanceAtHeight(ctx, req.(*BalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_GetMempool_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).GetMempool(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/GetMempool",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).GetMempool(ctx, req.(*PageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_SendTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Transaction)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).SendTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Query/SendTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).SendTransaction(ctx, req.(*Transaction))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_SubscribeTips_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Ack)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QueryServer).SubscribeTips(m, &querySubscribeTipsServer{stream})
+}
+
+type Query_SubscribeTipsServer interface {
+	Send(*TipEvent) error
+	grpc.ServerStream
+}
+
+type querySubscribeTipsServer struct {
+	grpc.ServerStream
+}
+
+func (x *querySubscribeTipsServer) Send(m *TipEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Query_SubscribeReorgs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Ack)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QueryServer).SubscribeReorgs(m, &querySubscribeReorgsServer{stream})
+}
+
+type Query_SubscribeReorgsServer interface {
+	Send(*ReorgEvent) error
+	grpc.ServerStream
+}
+
+type querySubscribeReorgsServer struct {
+	grpc.ServerStream
+}
+
+func (x *querySubscribeReorgsServer) Send(m *ReorgEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Query_SubscribeMempool_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Ack)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QueryServer).SubscribeMempool(m, &querySubscribeMempoolServer{stream})
+}
+
+type Query_SubscribeMempoolServer interface {
+	Send(*MempoolEvent) error
+	grpc.ServerStream
+}
+
+type querySubscribeMempoolServer struct {
+	grpc.ServerStream
+}
+
+func (x *querySubscribeMempoolServer) Send(m *MempoolEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Query_SubscribePayments_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AddressList)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QueryServer).SubscribePayments(m, &querySubscribePaymentsServer{stream})
+}
+
+type Query_SubscribePaymentsServer interface {
+	Send(*PaymentEvent) error
+	grpc.ServerStream
+}
+
+type querySubscribePaymentsServer struct {
+	grpc.ServerStream
+}
+
+func (x *querySubscribePaymentsServer) Send(m *PaymentEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Query_SubscribePeers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Ack)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QueryServer).SubscribePeers(m, &querySubscribePeersServer{stream})
+}
+
+type Query_SubscribePeersServer interface {
+	Send(*PeerEvent) error
+	grpc.ServerStream
+}
+
+type querySubscribePeersServer struct {
+	grpc.ServerStream
+}
+
+func (x *querySubscribePeersServer) Send(m *PeerEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Query_ServiceDesc is the grpc.ServiceDesc for Query service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Query_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "Query",
+	HandlerType: (*QueryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetTip",
+			Handler:    _Query_GetTip_Handler,
+		},
+		{
+			MethodName: "GetBlockByHash",
+			Handler:    _Query_GetBlockByHash_Handler,
+		},
+		{
+			MethodName: "GetBlockByHeight",
+			Handler:    _Query_GetBlockByHeight_Handler,
+		},
+		{
+			MethodName: "ListBlocks",
+			Handler:    _Query_ListBlocks_Handler,
+		},
+		{
+			MethodName: "GetTransaction",
+			Handler:    _Query_GetTransaction_Handler,
+		},
+		{
+			MethodName: "GetUTXOs",
+			Handler:    _Query_GetUTXOs_Handler,
+		},
+		{
+			MethodName: "GetBalance",
+			Handler:    _Query_GetBalance_Handler,
+		},
+		{
+			MethodName: "GetAddressHistory",
+			Handler:    _Query_GetAddressHistory_Handler,
+		},
+		{
+			MethodName: "GetBalanceAtHeight",
+			Handler:    _Query_GetBalanceAtHeight_Handler,
+		},
+		{
+			MethodName: "GetMempool",
+			Handler:    _Query_GetMempool_Handler,
+		},
+		{
+			MethodName: "SendTransaction",
+			Handler:    _Query_SendTransaction_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeTips",
+			Handler:       _Query_SubscribeTips_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeReorgs",
+			Handler:       _Query_SubscribeReorgs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeMempool",
+			Handler:       _Query_SubscribeMempool_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribePayments",
+			Handler:       _Query_SubscribePayments_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribePeers",
+			Handler:       _Query_SubscribePeers_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "protobuf/query.proto",
+}
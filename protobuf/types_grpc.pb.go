@@ -22,8 +22,11 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type NodeClient interface {
+	Challenge(ctx context.Context, in *ChallengeMsg, opts ...grpc.CallOption) (*ChallengeMsg, error)
 	Handshake(ctx context.Context, in *HandshakeMsg, opts ...grpc.CallOption) (*HandshakeMsg, error)
 	HandleTransaction(ctx context.Context, in *Transaction, opts ...grpc.CallOption) (*Ack, error)
+	Ping(ctx context.Context, in *Ack, opts ...grpc.CallOption) (*Ack, error)
+	GetBlocks(ctx context.Context, in *BlockRange, opts ...grpc.CallOption) (Node_GetBlocksClient, error)
 }
 
 type nodeClient struct {
@@ -34,6 +37,15 @@ func NewNodeClient(cc grpc.ClientConnInterface) NodeClient {
 	return &nodeClient{cc}
 }
 
+func (c *nodeClient) Challenge(ctx context.Context, in *ChallengeMsg, opts ...grpc.CallOption) (*ChallengeMsg, error) {
+	out := new(ChallengeMsg)
+	err := c.cc.Invoke(ctx, "/Node/Challenge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *nodeClient) Handshake(ctx context.Context, in *HandshakeMsg, opts ...grpc.CallOption) (*HandshakeMsg, error) {
 	out := new(HandshakeMsg)
 	err := c.cc.Invoke(ctx, "/Node/Handshake", in, out, opts...)
@@ -52,12 +64,56 @@ func (c *nodeClient) HandleTransaction(ctx context.Context, in *Transaction, opt
 	return out, nil
 }
 
+func (c *nodeClient) Ping(ctx context.Context, in *Ack, opts ...grpc.CallOption) (*Ack, error) {
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, "/Node/Ping", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) GetBlocks(ctx context.Context, in *BlockRange, opts ...grpc.CallOption) (Node_GetBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Node_ServiceDesc.Streams[0], "/Node/GetBlocks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &nodeGetBlocksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Node_GetBlocksClient interface {
+	Recv() (*Block, error)
+	grpc.ClientStream
+}
+
+type nodeGetBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *nodeGetBlocksClient) Recv() (*Block, error) {
+	m := new(Block)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // NodeServer is the server API for Node service.
 // All implementations must embed UnimplementedNodeServer
 // for forward compatibility
 type NodeServer interface {
+	Challenge(context.Context, *ChallengeMsg) (*ChallengeMsg, error)
 	Handshake(context.Context, *HandshakeMsg) (*HandshakeMsg, error)
 	HandleTransaction(context.Context, *Transaction) (*Ack, error)
+	Ping(context.Context, *Ack) (*Ack, error)
+	GetBlocks(*BlockRange, Node_GetBlocksServer) error
 	mustEmbedUnimplementedNodeServer()
 }
 
@@ -65,12 +121,21 @@ type NodeServer interface {
 type UnimplementedNodeServer struct {
 }
 
+func (UnimplementedNodeServer) Challenge(context.Context, *ChallengeMsg) (*ChallengeMsg, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Challenge not implemented")
+}
 func (UnimplementedNodeServer) Handshake(context.Context, *HandshakeMsg) (*HandshakeMsg, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Handshake not implemented")
 }
 func (UnimplementedNodeServer) HandleTransaction(context.Context, *Transaction) (*Ack, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method HandleTransaction not implemented")
 }
+func (UnimplementedNodeServer) Ping(context.Context, *Ack) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedNodeServer) GetBlocks(*BlockRange, Node_GetBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetBlocks not implemented")
+}
 func (UnimplementedNodeServer) mustEmbedUnimplementedNodeServer() {}
 
 // UnsafeNodeServer may be embedded to opt out of forward compatibility for this service.
@@ -84,6 +149,24 @@ func RegisterNodeServer(s grpc.ServiceRegistrar, srv NodeServer) {
 	s.RegisterService(&Node_ServiceDesc, srv)
 }
 
+func _Node_Challenge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChallengeMsg)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).Challenge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/Challenge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).Challenge(ctx, req.(*ChallengeMsg))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Node_Handshake_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(HandshakeMsg)
 	if err := dec(in); err != nil {
@@ -120,6 +203,45 @@ func _Node_HandleTransaction_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Node_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Ack)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/Ping",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).Ping(ctx, req.(*Ack))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_GetBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlockRange)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NodeServer).GetBlocks(m, &nodeGetBlocksServer{stream})
+}
+
+type Node_GetBlocksServer interface {
+	Send(*Block) error
+	grpc.ServerStream
+}
+
+type nodeGetBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *nodeGetBlocksServer) Send(m *Block) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Node_ServiceDesc is the grpc.ServiceDesc for Node service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -127,6 +249,10 @@ var Node_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "Node",
 	HandlerType: (*NodeServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Challenge",
+			Handler:    _Node_Challenge_Handler,
+		},
 		{
 			MethodName: "Handshake",
 			Handler:    _Node_Handshake_Handler,
@@ -135,7 +261,17 @@ var Node_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "HandleTransaction",
 			Handler:    _Node_HandleTransaction_Handler,
 		},
+		{
+			MethodName: "Ping",
+			Handler:    _Node_Ping_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetBlocks",
+			Handler:       _Node_GetBlocks_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "protobuf/types.proto",
 }
@@ -26,3 +26,29 @@ func HashHeaderSHA256(header *proto.Header) []byte {
 	hash := sha256.Sum256(b)
 	return hash[:]
 }
+
+// MerkleRoot returns the root of the Merkle tree whose leaves are the hashes of transactions. Each level hashes the
+// concatenated pairs of hashes of the level below, the last hash of a level of odd size being paired with itself.
+// Without transactions, the root is all zeros.
+func MerkleRoot(transactions []*proto.Transaction) []byte {
+	if len(transactions) == 0 {
+		return make([]byte, sha256.Size)
+	}
+
+	level := make([][]byte, len(transactions))
+	for i, transaction := range transactions {
+		level[i] = HashTransactionSHA256(transaction)
+	}
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][]byte, len(level)/2)
+		for i := range next {
+			hash := sha256.Sum256(append(append([]byte{}, level[2*i]...), level[2*i+1]...))
+			next[i] = hash[:]
+		}
+		level = next
+	}
+	return level[0]
+}
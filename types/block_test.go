@@ -1,17 +1,21 @@
-package types
+package types_test
 
 import (
+	"crypto/sha256"
 	"testing"
 
 	"github.com/fabrizioperria/blockchain/crypto"
+	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
 	"github.com/fabrizioperria/blockchain/utils"
 	"github.com/stretchr/testify/assert"
+	pb "google.golang.org/protobuf/proto"
 )
 
 func TestHashBlockSHA256(t *testing.T) {
 	block := utils.GenerateBlock(t, 1)
 
-	hash := HashBlockSHA256(block)
+	hash := types.HashBlockSHA256(block)
 	assert.Equal(t, 32, len(hash))
 }
 
@@ -20,8 +24,49 @@ func TestSignBlock(t *testing.T) {
 	privateKey := crypto.GeneratePrivateKey()
 	publicKey := privateKey.Public()
 
-	signature := SignBlock(block, privateKey)
+	signature := types.SignBlock(block, privateKey)
 	assert.NotNil(t, signature)
 	assert.Equal(t, 64, len(signature.Bytes()))
-	assert.True(t, signature.Verify(publicKey, HashBlockSHA256(block)))
+	assert.True(t, signature.Verify(publicKey, types.HashBlockSHA256(block)))
+}
+
+func TestMerkleRoot(t *testing.T) {
+	assert.Equal(t, make([]byte, 32), types.MerkleRoot(nil))
+
+	txs := utils.UnsignedTransactions(3, 1)
+	assert.Equal(t, types.HashTransactionSHA256(txs[0]), types.MerkleRoot(txs[:1]))
+	pair := func(a, b []byte) []byte {
+		hash := sha256.Sum256(append(append([]byte{}, a...), b...))
+		return hash[:]
+	}
+	a, b, c := types.HashTransactionSHA256(txs[0]), types.HashTransactionSHA256(txs[1]), types.HashTransactionSHA256(txs[2])
+	assert.Equal(t, pair(a, b), types.MerkleRoot(txs[:2]))
+	assert.Equal(t, pair(pair(a, b), pair(c, c)), types.MerkleRoot(txs))
+
+	txs[2].Outputs[0].Amount++
+	assert.NotEqual(t, pair(pair(a, b), pair(c, c)), types.MerkleRoot(txs))
+	assert.NotEqual(t, types.MerkleRoot(txs), types.MerkleRoot([]*proto.Transaction{txs[1], txs[0], txs[2]}))
+}
+
+func FuzzHashBlock(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		block := &proto.Block{}
+		if err := pb.Unmarshal(data, block); err != nil {
+			return
+		}
+		assert.Len(t, types.HashBlockSHA256(block), 32)
+	})
+}
+
+func BenchmarkHashHeaderSHA256(b *testing.B) {
+	header := &proto.Header{
+		Version:      1,
+		Height:       100,
+		PreviousHash: make([]byte, 32),
+		MerkleRoot:   make([]byte, 32),
+		Timestamp:    1,
+	}
+	for i := 0; i < b.N; i++ {
+		types.HashHeaderSHA256(header)
+	}
 }
@@ -9,7 +9,7 @@ import (
 )
 
 func SignTransaction(transaction *proto.Transaction, privateKey *crypto.PrivateKey) *crypto.Signature {
-	hash := HashTransactionSHA256(transaction)
+	hash := HashTransactionForSigning(transaction)
 	signature := privateKey.Sign(hash)
 
 	return signature
@@ -25,18 +25,44 @@ func HashTransactionSHA256(transaction *proto.Transaction) []byte {
 	return hash[:]
 }
 
-func VerifyTransaction(transaction *proto.Transaction) bool {
-	for _, input := range transaction.Inputs {
-		signature := crypto.SignatureFromBytes(input.Signature)
-		publicKey := crypto.PublicKeyFromBytes(input.PublicKey)
-
-		// i need to set the input's signatures to nil before verifying the transaction; this is because the signature is not part of the transaction
+// HashTransactionForSigning hashes the transaction without its signatures: they cannot be part of what they sign,
+// and leaving them out lets every input be signed independently.
+//
+// Every input signs this same hash. Before protocol version 2.0.0, the input at index i was checked against the
+// hash of the transaction with the signatures of inputs 0 to i removed, those of the following inputs left in
+// place, so a transaction with more than one input signed for one scheme fails verification under the other.
+// Transactions with a single input hash the same under both.
+func HashTransactionForSigning(transaction *proto.Transaction) []byte {
+	unsigned := pb.Clone(transaction).(*proto.Transaction)
+	for _, input := range unsigned.Inputs {
 		input.Signature = nil
+	}
 
-		hash := HashTransactionSHA256(transaction)
-		if !signature.Verify(publicKey, hash) {
+	return HashTransactionSHA256(unsigned)
+}
+
+// VerifyTransaction tells whether every input of transaction is signed by its public key. Malformed keys
+// and signatures do not verify.
+func VerifyTransaction(transaction *proto.Transaction) bool {
+	hash := HashTransactionForSigning(transaction)
+	for _, input := range transaction.Inputs {
+		if !VerifyInput(input, hash) {
 			return false
 		}
 	}
 	return true
 }
+
+// VerifyInput tells whether input signs hash, the HashTransactionForSigning of its transaction, with its public key.
+func VerifyInput(input *proto.TxInput, hash []byte) bool {
+	signature, err := crypto.SignatureFromBytes(input.Signature)
+	if err != nil {
+		return false
+	}
+	publicKey, err := crypto.PublicKeyFromBytes(input.PublicKey)
+	if err != nil {
+		return false
+	}
+
+	return signature.Verify(publicKey, hash)
+}
@@ -1,11 +1,15 @@
-package types
+package types_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/fabrizioperria/blockchain/crypto"
 	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
+	"github.com/fabrizioperria/blockchain/utils"
 	"github.com/stretchr/testify/assert"
+	pb "google.golang.org/protobuf/proto"
 )
 
 func TestNewTransaction(t *testing.T) {
@@ -42,8 +46,107 @@ func TestNewTransaction(t *testing.T) {
 		Outputs: []*proto.TxOutput{output1, output2},
 	}
 
-	signature := SignTransaction(transaction, fromPrivateKey)
+	signature := types.SignTransaction(transaction, fromPrivateKey)
 	input.Signature = signature.Bytes()
 
-	assert.True(t, VerifyTransaction(transaction))
+	assert.True(t, types.VerifyTransaction(transaction))
+}
+
+func TestVerifyTransactionWithMultipleInputs(t *testing.T) {
+	transaction := utils.SignedTransactions(1, 2)[0]
+	signed := pb.Clone(transaction)
+
+	assert.True(t, types.VerifyTransaction(transaction))
+	assert.True(t, pb.Equal(signed, transaction), "verifying must not strip the signatures")
+
+	transaction.Outputs[0].Amount = 11
+	assert.False(t, types.VerifyTransaction(transaction))
+}
+
+func TestHashTransactionForSigningIgnoresSignatures(t *testing.T) {
+	transaction := utils.UnsignedTransactions(1, 3)[0]
+	hash := types.HashTransactionForSigning(transaction)
+
+	for i, input := range transaction.Inputs {
+		input.Signature = []byte(fmt.Sprintf("signature %d", i))
+		assert.Equal(t, hash, types.HashTransactionForSigning(transaction))
+	}
+	assert.NotNil(t, transaction.Inputs[0].Signature, "hashing must not strip the signatures")
+
+	transaction.Inputs[0].PrevOutputIndex++
+	assert.NotEqual(t, hash, types.HashTransactionForSigning(transaction))
+}
+
+func TestSingleInputSigningHashIsUnchanged(t *testing.T) {
+	transaction := utils.SignedTransactions(1, 1)[0]
+	unsigned := pb.Clone(transaction).(*proto.Transaction)
+	unsigned.Inputs[0].Signature = nil
+
+	// before protocol version 2.0.0 an input signed the transaction with its own signature removed
+	assert.Equal(t, types.HashTransactionSHA256(unsigned), types.HashTransactionForSigning(transaction))
+}
+
+func TestVersion1MultiInputSignaturesDoNotVerify(t *testing.T) {
+	keys := []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+	transaction := utils.UnsignedTransactions(1, 2)[0]
+	for i, key := range keys {
+		transaction.Inputs[i].PublicKey = key.Public().Bytes()
+	}
+	// before protocol version 2.0.0 the input at index i signed the transaction with the signatures of inputs 0
+	// to i removed: the second input signs it unsigned, the first one along with the signature of the second
+	transaction.Inputs[1].Signature = keys[1].Sign(types.HashTransactionSHA256(transaction)).Bytes()
+	transaction.Inputs[0].Signature = keys[0].Sign(types.HashTransactionSHA256(transaction)).Bytes()
+
+	assert.False(t, types.VerifyTransaction(transaction))
+	assert.False(t, types.VerifyInput(transaction.Inputs[0], types.HashTransactionForSigning(transaction)))
+	assert.True(t, types.VerifyInput(transaction.Inputs[1], types.HashTransactionForSigning(transaction)))
+}
+
+func FuzzVerifyTransaction(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		transaction := &proto.Transaction{}
+		if err := pb.Unmarshal(data, transaction); err != nil {
+			return
+		}
+		assert.Len(t, types.HashTransactionSHA256(transaction), 32)
+		types.VerifyTransaction(transaction)
+	})
+}
+
+var benchmarkInputs = []int{1, 4, 16, 64}
+
+func BenchmarkHashTransactionSHA256(b *testing.B) {
+	for _, inputs := range benchmarkInputs {
+		transaction := utils.SignedTransactions(1, inputs)[0]
+		b.Run(fmt.Sprintf("inputs=%d", inputs), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				types.HashTransactionSHA256(transaction)
+			}
+		})
+	}
+}
+
+func BenchmarkSignTransaction(b *testing.B) {
+	key := crypto.GeneratePrivateKey()
+	for _, inputs := range benchmarkInputs {
+		transaction := utils.SignedTransactions(1, inputs)[0]
+		b.Run(fmt.Sprintf("inputs=%d", inputs), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				types.SignTransaction(transaction, key)
+			}
+		})
+	}
+}
+
+func BenchmarkVerifyTransaction(b *testing.B) {
+	for _, inputs := range benchmarkInputs {
+		transaction := utils.SignedTransactions(1, inputs)[0]
+		b.Run(fmt.Sprintf("inputs=%d", inputs), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if !types.VerifyTransaction(transaction) {
+					b.Fatal("transaction does not verify")
+				}
+			}
+		})
+	}
 }
@@ -8,10 +8,12 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/fabrizioperria/blockchain/crypto"
 	proto "github.com/fabrizioperria/blockchain/protobuf"
+	"github.com/fabrizioperria/blockchain/types"
 )
 
-func RandomHash(t *testing.T) []byte {
+func RandomHash(t testing.TB) []byte {
 	hash := make([]byte, 32)
 	n, err := crand.Read(hash)
 	assert.NoError(t, err)
@@ -20,14 +22,105 @@ func RandomHash(t *testing.T) []byte {
 	return hash
 }
 
-func GenerateBlock(t *testing.T, height int32) *proto.Block {
+// GenerateBlock returns a block at height extending a random parent. It has no transactions, and the Merkle root
+// of none, which blocks must carry since protocol version 2.0.0.
+func GenerateBlock(t testing.TB, height int32) *proto.Block {
 	return &proto.Block{
 		Header: &proto.Header{
 			Version:      1,
 			Height:       height,
 			PreviousHash: RandomHash(t),
-			MerkleRoot:   RandomHash(t),
+			MerkleRoot:   types.MerkleRoot(nil),
 			Timestamp:    time.Now().UnixNano(),
 		},
 	}
 }
+
+// GenerateBlocks returns count distinct blocks, at heights 0 to count-1. They do not follow each other.
+func GenerateBlocks(t testing.TB, count int) []*proto.Block {
+	blocks := make([]*proto.Block, count)
+	for i := range blocks {
+		blocks[i] = GenerateBlock(t, int32(i))
+	}
+	return blocks
+}
+
+// ChildBlock returns a block extending parent with transactions, and their Merkle root.
+func ChildBlock(t testing.TB, parent *proto.Block, transactions ...*proto.Transaction) *proto.Block {
+	block := GenerateBlock(t, parent.Header.Height+1)
+	block.Header.PreviousHash = types.HashBlockSHA256(parent)
+	block.Transaction = transactions
+	block.Header.MerkleRoot = types.MerkleRoot(transactions)
+	return block
+}
+
+// Branch returns count blocks, each the child of the previous one, the first one a child of parent.
+func Branch(t testing.TB, parent *proto.Block, count int) []*proto.Block {
+	blocks := []*proto.Block{}
+	for i := 0; i < count; i++ {
+		parent = ChildBlock(t, parent)
+		blocks = append(blocks, parent)
+	}
+	return blocks
+}
+
+// MintTransaction returns a transaction without inputs paying amount to to.
+func MintTransaction(to *crypto.Address, amount int64) *proto.Transaction {
+	return &proto.Transaction{
+		Version: 1,
+		Outputs: []*proto.TxOutput{{Amount: amount, DestAddress: to.Bytes()}},
+	}
+}
+
+// SpendTransaction returns a transaction spending the output at index of prevTx, owned by from, into outputs.
+func SpendTransaction(from *crypto.PrivateKey, prevTx *proto.Transaction, index int32, outputs ...*proto.TxOutput) *proto.Transaction {
+	tx := &proto.Transaction{
+		Version: 1,
+		Inputs: []*proto.TxInput{{
+			PreviousTxHash:  types.HashTransactionSHA256(prevTx),
+			PrevOutputIndex: index,
+			PublicKey:       from.Public().Bytes(),
+		}},
+		Outputs: outputs,
+	}
+	tx.Inputs[0].Signature = types.SignTransaction(tx, from).Bytes()
+	return tx
+}
+
+// UnsignedTransactions returns count distinct transactions of inputs inputs each, spending outputs that do not
+// exist.
+func UnsignedTransactions(count int, inputs int) []*proto.Transaction {
+	address := crypto.GeneratePrivateKey().Public().Address().Bytes()
+	txs := make([]*proto.Transaction, count)
+	for i := range txs {
+		txs[i] = &proto.Transaction{
+			Version: 1,
+			Outputs: []*proto.TxOutput{{Amount: 10, DestAddress: address}},
+		}
+		for j := 0; j < inputs; j++ {
+			txs[i].Inputs = append(txs[i].Inputs, &proto.TxInput{
+				PreviousTxHash:  make([]byte, 32),
+				PrevOutputIndex: int32(i*inputs + j),
+			})
+		}
+	}
+	return txs
+}
+
+// SignedTransactions returns the UnsignedTransactions, every input signed by a key of its own.
+func SignedTransactions(count int, inputs int) []*proto.Transaction {
+	keys := make([]*crypto.PrivateKey, inputs)
+	for j := range keys {
+		keys[j] = crypto.GeneratePrivateKey()
+	}
+	txs := UnsignedTransactions(count, inputs)
+	for _, tx := range txs {
+		for j, key := range keys {
+			tx.Inputs[j].PublicKey = key.Public().Bytes()
+		}
+		for j, key := range keys {
+			tx.Inputs[j].Signature = types.SignTransaction(tx, key).Bytes()
+		}
+	}
+	return txs
+}